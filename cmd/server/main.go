@@ -3,23 +3,43 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"task-manager/internal/apiversion"
+	"task-manager/internal/auth"
 	"task-manager/internal/handlers"
 	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"task-manager/internal/oidc"
 	"task-manager/internal/repository"
 	"task-manager/internal/services"
+	"task-manager/internal/storage"
 	worker "task-manager/internal/workers"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
+// defaultTrashRetention is how long deleted tasks can be restored before
+// they are purged, unless TRASH_RETENTION says otherwise.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// unversionedDeprecated is when the routes outside /v1 were deprecated in its
+// favour.
+var unversionedDeprecated = time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+
+// defaultIdempotencyWindow is how long the response to a write sent with an
+// Idempotency-Key is replayed, unless IDEMPOTENCY_KEY_TTL says otherwise.
+const defaultIdempotencyWindow = 24 * time.Hour
+
 func main() {
 	dbURL := os.Getenv("DATABASE_URL")
 	if dbURL == "" {
@@ -36,19 +56,217 @@ func main() {
 		log.Fatal("Failed to ping database:", err)
 	}
 
+	signingKeys, err := loadSigningKeys()
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+
+	hierarchyPolicy, err := loadHierarchyPolicy()
+	if err != nil {
+		log.Fatal("Invalid subtask policy:", err)
+	}
+
+	blockerPolicy, err := loadBlockerPolicy()
+	if err != nil {
+		log.Fatal("Invalid blocker policy:", err)
+	}
+
+	trashRetention := defaultTrashRetention
+	if v := os.Getenv("TRASH_RETENTION"); v != "" {
+		trashRetention, err = time.ParseDuration(v)
+		if err != nil || trashRetention <= 0 {
+			log.Fatalf("Invalid TRASH_RETENTION %q", v)
+		}
+	}
+
+	idempotencyWindow := defaultIdempotencyWindow
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		idempotencyWindow, err = time.ParseDuration(v)
+		if err != nil || idempotencyWindow <= 0 {
+			log.Fatalf("Invalid IDEMPOTENCY_KEY_TTL %q", v)
+		}
+	}
+
 	taskRepo := repository.NewTaskRepository(db)
-	taskService := services.NewTaskService(taskRepo)
-	taskHandler := handlers.NewTaskHandler(taskService)
+	dependencyRepo := repository.NewDependencyRepository(db)
+	// newTaskService builds the task service on the given repositories, so
+	// atomic batches can bind all of them to their transaction.
+	newTaskService := func(tasks repository.TaskRepository, deps repository.DependencyRepository) services.TaskService {
+		return services.NewTaskService(tasks,
+			services.WithHierarchyPolicy(hierarchyPolicy),
+			services.WithDependencies(deps, blockerPolicy),
+		)
+	}
+	taskService := newTaskService(taskRepo, dependencyRepo)
+	requireIfMatch, err := loadRequireIfMatch()
+	if err != nil {
+		log.Fatal("Invalid If-Match setting:", err)
+	}
+	taskHandler := handlers.NewTaskHandler(taskService, handlers.RequireIfMatch(requireIfMatch))
 
-	router := mux.NewRouter()
+	tagRepo := repository.NewTagRepository(db)
+	tagService := services.NewTagService(tagRepo, taskRepo)
+	tagHandler := handlers.NewTagHandler(tagService)
+
+	maxBatchSize, err := loadMaxBatchSize()
+	if err != nil {
+		log.Fatal("Invalid bulk settings:", err)
+	}
+	bulkService := services.NewBulkService(repository.NewTxRunner(db), taskRepo, tagRepo, dependencyRepo,
+		func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) (services.TaskService, services.TagService) {
+			return newTaskService(tasks, deps), services.NewTagService(tags, tasks)
+		},
+		maxBatchSize,
+	)
+	bulkHandler := handlers.NewBulkHandler(bulkService)
+
+	dependencyService := services.NewDependencyService(dependencyRepo, taskRepo)
+	dependencyHandler := handlers.NewDependencyHandler(dependencyService)
+
+	commentRepo := repository.NewCommentRepository(db)
+	commentService := services.NewCommentService(commentRepo, taskRepo)
+	commentHandler := handlers.NewCommentHandler(commentService)
+
+	blobStore, attachmentLimits, err := loadAttachmentStorage()
+	if err != nil {
+		log.Fatal("Failed to set up attachment storage:", err)
+	}
+	attachmentRepo := repository.NewAttachmentRepository(db)
+	attachmentService := services.NewAttachmentService(attachmentRepo, taskRepo, blobStore, attachmentLimits)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService, attachmentLimits.MaxSize)
+
+	taskEventRepo := repository.NewTaskEventRepository(db)
+	auditService := services.NewAuditService(taskEventRepo, taskRepo)
+	auditHandler := handlers.NewAuditHandler(auditService)
+
+	userRepo := repository.NewUserRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	userService := services.NewUserService(userRepo, tokenRepo)
+	tokenService := services.NewTokenService(tokenRepo, userRepo, signingKeys)
+	authHandler := handlers.NewAuthHandler(userService, tokenService)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	authMiddleware := middleware.NewAuthenticator(signingKeys, tokenService, apiKeyService)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	idempotency := middleware.NewIdempotency(idempotencyRepo, idempotencyWindow, attachmentLimits.MaxSize+handlers.MultipartOverhead)
+
+	userHandler := handlers.NewUserHandler(userService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	jwksHandler := handlers.NewJWKSHandler(signingKeys)
+
+	readers := []models.Role{models.RoleAdmin, models.RoleMember, models.RoleViewer}
+	writers := []models.Role{models.RoleAdmin, models.RoleMember}
+	admins := []models.Role{models.RoleAdmin}
+
+	// protected authenticates the caller and then only lets the given roles
+	// through. Writes can be made safe to retry with an Idempotency-Key.
+	protected := func(h http.HandlerFunc, roles []models.Role) http.Handler {
+		return authMiddleware.Authenticate(middleware.RequireRole(roles...)(idempotency.Handle(h)))
+	}
+	// sensitive is protected without Idempotency-Key support, for writes
+	// whose responses carry credentials or end sessions; those must never
+	// be kept in the idempotency table to be replayed.
+	sensitive := func(h http.HandlerFunc, roles []models.Role) http.Handler {
+		return authMiddleware.Authenticate(middleware.RequireRole(roles...)(h))
+	}
+	// keyManagement is sensitive for callers signed in with a password or
+	// single sign-on only; API keys may not create or revoke keys.
+	keyManagement := func(h http.HandlerFunc, roles []models.Role) http.Handler {
+		return authMiddleware.Authenticate(middleware.RequireRole(roles...)(middleware.RequireSession(h)))
+	}
+
+	var oidcHandler *handlers.OIDCHandler
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		discoveryCtx, discoveryCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.NewProvider(discoveryCtx, oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		})
+		discoveryCancel()
+		if err != nil {
+			log.Fatal("Failed to set up OIDC provider:", err)
+		}
+
+		identityRepo := repository.NewIdentityRepository(db)
+		oidcService := services.NewOIDCService(provider, identityRepo, userRepo, tokenService)
+		oidcHandler = handlers.NewOIDCHandler(oidcService)
+	}
+
+	versionPolicies, err := loadVersionPolicies()
+	if err != nil {
+		log.Fatal("Invalid API version settings:", err)
+	}
+
+	// routes registers the API on r. Every version serves the same routes;
+	// the handlers pick the response shapes of the version a request is for.
+	routes := func(r *mux.Router) {
+		r.HandleFunc("/register", authHandler.Register).Methods("POST")
+		r.HandleFunc("/login", authHandler.Login).Methods("POST")
+		r.HandleFunc("/token/refresh", authHandler.RefreshToken).Methods("POST")
+		r.Handle("/logout", sensitive(authHandler.Logout, readers)).Methods("POST")
+		r.Handle("/password", sensitive(authHandler.ChangePassword, readers)).Methods("PUT")
+		r.Handle("/api-keys", keyManagement(apiKeyHandler.CreateAPIKey, readers)).Methods("POST")
+		r.Handle("/api-keys", protected(apiKeyHandler.ListAPIKeys, readers)).Methods("GET")
+		r.Handle("/api-keys/{id}", keyManagement(apiKeyHandler.RevokeAPIKey, readers)).Methods("DELETE")
+		r.Handle("/users/{id}/role", protected(userHandler.UpdateRole, admins)).Methods("PUT")
+		r.Handle("/audit/events", protected(auditHandler.SearchEvents, admins)).Methods("GET")
+		if oidcHandler != nil {
+			r.HandleFunc("/oidc/login", oidcHandler.Login).Methods("GET")
+			r.HandleFunc("/oidc/callback", oidcHandler.Callback).Methods("GET")
+		}
+
+		r.Handle("/tasks", protected(taskHandler.CreateTask, writers)).Methods("POST")
+		r.Handle("/tasks", protected(taskHandler.GetAllTasks, readers)).Methods("GET")
+		r.Handle("/tasks/bulk", protected(bulkHandler.ExecuteBulk, writers)).Methods("POST")
+		// Registered before /tasks/{id} so "next" is not taken for a task ID.
+		r.Handle("/tasks/search", protected(taskHandler.SearchTasks, readers)).Methods("GET")
+		r.Handle("/tasks/next", protected(dependencyHandler.GetNextTasks, readers)).Methods("GET")
+		r.Handle("/tasks/{id}", protected(taskHandler.GetTask, readers)).Methods("GET")
+		r.Handle("/tasks/{id}", protected(taskHandler.UpdateTask, writers)).Methods("PUT")
+		r.Handle("/tasks/{id}", protected(taskHandler.PatchTask, writers)).Methods("PATCH")
+		r.Handle("/tasks/{id}/complete", protected(taskHandler.MarkTaskComplete, writers)).Methods("PATCH")
+		r.Handle("/tasks/{id}", protected(taskHandler.DeleteTask, writers)).Methods("DELETE")
+		r.Handle("/tasks/{id}/restore", protected(taskHandler.RestoreTask, writers)).Methods("POST")
+		r.Handle("/trash", protected(taskHandler.GetTrash, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/history", protected(auditHandler.GetTaskHistory, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/children", protected(taskHandler.GetTaskChildren, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/subtree", protected(taskHandler.GetTaskSubtree, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/occurrences", protected(taskHandler.PreviewOccurrences, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/recurrence", protected(taskHandler.StopRecurrence, writers)).Methods("DELETE")
+		r.Handle("/tasks/{id}/dependencies", protected(dependencyHandler.GetBlockers, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/dependencies/{blockerId}", protected(dependencyHandler.AddDependency, writers)).Methods("PUT")
+		r.Handle("/tasks/{id}/dependencies/{blockerId}", protected(dependencyHandler.RemoveDependency, writers)).Methods("DELETE")
+		r.Handle("/tasks/{id}/comments", protected(commentHandler.AddComment, writers)).Methods("POST")
+		r.Handle("/tasks/{id}/comments", protected(commentHandler.GetComments, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/comments/{commentId}", protected(commentHandler.UpdateComment, writers)).Methods("PUT")
+		r.Handle("/tasks/{id}/comments/{commentId}", protected(commentHandler.DeleteComment, writers)).Methods("DELETE")
+		r.Handle("/tasks/{id}/attachments", protected(attachmentHandler.UploadAttachment, writers)).Methods("POST")
+		r.Handle("/tasks/{id}/attachments", protected(attachmentHandler.GetAttachments, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/attachments/{attachmentId}", protected(attachmentHandler.DownloadAttachment, readers)).Methods("GET")
+		r.Handle("/tasks/{id}/attachments/{attachmentId}", protected(attachmentHandler.DeleteAttachment, writers)).Methods("DELETE")
+		r.Handle("/tasks/{id}/tags/{tagId}", protected(tagHandler.AttachTag, writers)).Methods("PUT")
+		r.Handle("/tasks/{id}/tags/{tagId}", protected(tagHandler.DetachTag, writers)).Methods("DELETE")
+
+		r.Handle("/tags", protected(tagHandler.CreateTag, writers)).Methods("POST")
+		r.Handle("/tags", protected(tagHandler.GetAllTags, readers)).Methods("GET")
+		r.Handle("/tags/{id}", protected(tagHandler.UpdateTag, writers)).Methods("PUT")
+		r.Handle("/tags/{id}", protected(tagHandler.DeleteTag, writers)).Methods("DELETE")
+	}
 
-	router.HandleFunc("/login", handlers.LoginHandler).Methods("POST")
-	router.Handle("/tasks", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.CreateTask))).Methods("POST")
-	router.Handle("/tasks", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.GetAllTasks))).Methods("GET")
-	router.Handle("/tasks/{id}", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.GetTask))).Methods("GET")
-	router.Handle("/tasks/{id}", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.UpdateTask))).Methods("PUT")
-	router.Handle("/tasks/{id}/complete", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.MarkTaskComplete))).Methods("PATCH")
-	router.Handle("/tasks/{id}", middleware.JWTMiddleware(http.HandlerFunc(taskHandler.DeleteTask))).Methods("DELETE")
+	router := mux.NewRouter()
+	router.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+	// Versioned mounts come first so their prefixes are never taken for
+	// unversioned routes.
+	for _, policy := range versionPolicies {
+		mount := router.NewRoute().Subrouter()
+		if policy.Prefix != "" {
+			mount = router.PathPrefix(policy.Prefix).Subrouter()
+		}
+		mount.Use(middleware.APIVersion(policy))
+		routes(mount)
+	}
 
 	reminderWorker := worker.NewReminderWorker(
 		taskService,
@@ -61,6 +279,15 @@ func main() {
 
 	go reminderWorker.Start(ctx)
 
+	tokenCleanupWorker := worker.NewTokenCleanupWorker(tokenRepo, time.Hour)
+	go tokenCleanupWorker.Start(ctx)
+
+	trashPurgeWorker := worker.NewTrashPurgeWorker(taskRepo, blobStore, trashRetention, time.Hour)
+	go trashPurgeWorker.Start(ctx)
+
+	idempotencyCleanupWorker := worker.NewIdempotencyCleanupWorker(idempotencyRepo, time.Hour)
+	go idempotencyCleanupWorker.Start(ctx)
+
 	// Start server
 	server := &http.Server{
 		Addr:    ":8080",
@@ -89,3 +316,156 @@ func main() {
 
 	log.Println("Server exited")
 }
+
+// loadSigningKeys reads the JWT signing keys from JWT_KEYS_DIR and signs with
+// JWT_ACTIVE_KID. Without a key directory a throwaway key is generated, so
+// tokens do not survive a restart; that is only suitable for development.
+func loadSigningKeys() (*auth.KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Println("JWT_KEYS_DIR not set, using an ephemeral signing key")
+		key, err := auth.GenerateEd25519Key("ephemeral")
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewKeySet(key.ID, key)
+	}
+
+	activeKID := os.Getenv("JWT_ACTIVE_KID")
+	if activeKID == "" {
+		return nil, errors.New("JWT_ACTIVE_KID environment variable not set")
+	}
+
+	return auth.LoadKeySet(dir, activeKID)
+}
+
+// loadHierarchyPolicy reads how deleting or completing a task treats its
+// subtasks from TASK_CHILDREN_ON_DELETE (orphan, cascade, restrict) and
+// TASK_CHILDREN_ON_COMPLETE (ignore, cascade, restrict).
+func loadHierarchyPolicy() (services.HierarchyPolicy, error) {
+	policy := services.DefaultHierarchyPolicy
+
+	switch v := services.ChildDeletePolicy(os.Getenv("TASK_CHILDREN_ON_DELETE")); v {
+	case "":
+	case services.DeleteOrphan, services.DeleteCascade, services.DeleteRestrict:
+		policy.OnDelete = v
+	default:
+		return policy, fmt.Errorf("unknown TASK_CHILDREN_ON_DELETE %q", v)
+	}
+
+	switch v := services.ChildCompletePolicy(os.Getenv("TASK_CHILDREN_ON_COMPLETE")); v {
+	case "":
+	case services.CompleteIgnore, services.CompleteCascade, services.CompleteRestrict:
+		policy.OnComplete = v
+	default:
+		return policy, fmt.Errorf("unknown TASK_CHILDREN_ON_COMPLETE %q", v)
+	}
+
+	return policy, nil
+}
+
+// loadBlockerPolicy reads TASK_OPEN_BLOCKERS (refuse or warn), which decides
+// whether a task may be started or completed while its blockers are open.
+func loadBlockerPolicy() (services.BlockerPolicy, error) {
+	switch v := services.BlockerPolicy(os.Getenv("TASK_OPEN_BLOCKERS")); v {
+	case "":
+		return services.BlockersRefuse, nil
+	case services.BlockersRefuse, services.BlockersWarn:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unknown TASK_OPEN_BLOCKERS %q", v)
+	}
+}
+
+// loadRequireIfMatch reads TASK_REQUIRE_IF_MATCH, which makes task writes
+// without an If-Match header fail instead of applying unconditionally.
+func loadRequireIfMatch() (bool, error) {
+	v := os.Getenv("TASK_REQUIRE_IF_MATCH")
+	if v == "" {
+		return false, nil
+	}
+	required, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid TASK_REQUIRE_IF_MATCH %q", v)
+	}
+	return required, nil
+}
+
+// loadMaxBatchSize reads BULK_MAX_OPERATIONS, the most operations one bulk
+// request may carry.
+func loadMaxBatchSize() (int, error) {
+	v := os.Getenv("BULK_MAX_OPERATIONS")
+	if v == "" {
+		return services.DefaultMaxBatchSize, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid BULK_MAX_OPERATIONS %q", v)
+	}
+	return n, nil
+}
+
+// loadVersionPolicies lists the mounts of the API: /v1 and /v2, then the
+// unversioned routes, which answer as v1 and are deprecated in its favour.
+// API_V1_DEPRECATED and API_V1_SUNSET (RFC 3339) announce the end of v1, and
+// API_UNVERSIONED_SUNSET that of the unversioned routes.
+func loadVersionPolicies() ([]middleware.VersionPolicy, error) {
+	v1 := middleware.VersionPolicy{Version: apiversion.V1, Prefix: apiversion.V1.Prefix()}
+	v2 := middleware.VersionPolicy{Version: apiversion.V2, Prefix: apiversion.V2.Prefix()}
+	unversioned := middleware.VersionPolicy{
+		Version:    apiversion.V1,
+		Deprecated: unversionedDeprecated,
+		Successor:  apiversion.V1.Prefix(),
+	}
+
+	dates := []struct {
+		env string
+		dst *time.Time
+	}{
+		{"API_V1_DEPRECATED", &v1.Deprecated},
+		{"API_V1_SUNSET", &v1.Sunset},
+		{"API_UNVERSIONED_SUNSET", &unversioned.Sunset},
+	}
+	for _, d := range dates {
+		if v := os.Getenv(d.env); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", d.env, v)
+			}
+			*d.dst = t
+		}
+	}
+	if !v1.Sunset.IsZero() && v1.Deprecated.IsZero() {
+		return nil, errors.New("API_V1_SUNSET is set without API_V1_DEPRECATED")
+	}
+	if !v1.Deprecated.IsZero() {
+		v1.Successor = apiversion.Latest.Prefix()
+	}
+
+	return []middleware.VersionPolicy{v1, v2, unversioned}, nil
+}
+
+// loadAttachmentStorage keeps attachments on the local filesystem under
+// ATTACHMENTS_DIR (default ./data/attachments), each at most
+// ATTACHMENT_MAX_BYTES in size.
+func loadAttachmentStorage() (storage.BlobStore, services.AttachmentLimits, error) {
+	limits := services.DefaultAttachmentLimits
+	if v := os.Getenv("ATTACHMENT_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return nil, limits, fmt.Errorf("invalid ATTACHMENT_MAX_BYTES %q", v)
+		}
+		limits.MaxSize = n
+	}
+
+	dir := os.Getenv("ATTACHMENTS_DIR")
+	if dir == "" {
+		dir = "data/attachments"
+	}
+	store, err := storage.NewLocalStore(dir)
+	if err != nil {
+		return nil, limits, err
+	}
+
+	return store, limits, nil
+}
@@ -0,0 +1,46 @@
+// Package apiversion tells handlers which version of the API a request was
+// made to, so that one set of handlers can serve every mounted version.
+package apiversion
+
+import (
+	"context"
+	"strconv"
+)
+
+// Version is a major version of the HTTP API, mounted under /v<N>.
+type Version int
+
+const (
+	V1 Version = 1
+	// V2 drops fields kept for old clients and puts listing metadata next to
+	// the data instead of among it.
+	V2 Version = 2
+)
+
+// Latest is the version new clients should use.
+const Latest = V2
+
+// Prefix is the path the version is mounted under, such as "/v1".
+func (v Version) Prefix() string {
+	return "/" + v.String()
+}
+
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the version of the request ctx belongs to. Requests
+// that did not come through a versioned mount, such as those to the
+// unversioned aliases, get V1.
+func FromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKey{}).(Version); ok {
+		return v
+	}
+	return V1
+}
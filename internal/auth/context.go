@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"time"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID   int64
+	Username string
+	Role     models.Role
+	// TokenID and ExpiresAt identify the access token the caller presented,
+	// so it can be revoked on logout.
+	TokenID   string
+	ExpiresAt time.Time
+	// APIKeyID is set when the caller authenticated with an API key instead of
+	// a JWT; ReadOnly is set when that key is restricted to reads.
+	APIKeyID int64
+	ReadOnly bool
+	// System marks internal callers such as background workers, which act on
+	// behalf of every user rather than a single one.
+	System bool
+}
+
+// SeesAllTasks reports whether the principal may act on every user's tasks
+// rather than only their own.
+func (p *Principal) SeesAllTasks() bool {
+	return p.System || p.Role == models.RoleAdmin
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// NewSystemContext returns a context for internal jobs that are not scoped to
+// any one user.
+func NewSystemContext(ctx context.Context) context.Context {
+	return NewContext(ctx, &Principal{System: true})
+}
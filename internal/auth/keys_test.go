@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"task-manager/internal/auth"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKey(t *testing.T, dir, kid string, key interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600))
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	writeKey(t, dir, "ed-1", edKey)
+	writeKey(t, dir, "rsa-1", rsaKey)
+
+	keys, err := auth.LoadKeySet(dir, "rsa-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"EdDSA", "RS256"}, keys.Algorithms())
+
+	signed, err := keys.Sign(jwt.MapClaims{"sub": "1"})
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, keys.Keyfunc, jwt.WithValidMethods(keys.Algorithms()))
+	require.NoError(t, err)
+	assert.Equal(t, "RS256", token.Method.Alg())
+	assert.Equal(t, "rsa-1", token.Header["kid"])
+
+	jwks := keys.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "ed-1", jwks.Keys[0].Kid)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "rsa-1", jwks.Keys[1].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+
+	_, err = auth.LoadKeySet(dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	oldKey, err := auth.GenerateEd25519Key("old")
+	require.NoError(t, err)
+	newKey, err := auth.GenerateEd25519Key("new")
+	require.NoError(t, err)
+
+	before, err := auth.NewKeySet("old", oldKey)
+	require.NoError(t, err)
+	oldToken, err := before.Sign(jwt.MapClaims{"sub": "1"})
+	require.NoError(t, err)
+
+	// New key active, old key kept for verification.
+	during, err := auth.NewKeySet("new", oldKey, newKey)
+	require.NoError(t, err)
+	_, err = jwt.Parse(oldToken, during.Keyfunc)
+	assert.NoError(t, err)
+
+	newToken, err := during.Sign(jwt.MapClaims{"sub": "1"})
+	require.NoError(t, err)
+	parsed, err := jwt.Parse(newToken, during.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, "new", parsed.Header["kid"])
+
+	// Old key retired.
+	after, err := auth.NewKeySet("new", newKey)
+	require.NoError(t, err)
+	_, err = jwt.Parse(oldToken, after.Keyfunc)
+	assert.ErrorIs(t, err, auth.ErrUnknownKey)
+}
+
+func TestKeyfuncRejectsHMAC(t *testing.T) {
+	key, err := auth.GenerateEd25519Key("k1")
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet("k1", key)
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "1"})
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(signed, keys.Keyfunc)
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type APIKeyHandler struct {
+	service services.APIKeyService
+}
+
+func NewAPIKeyHandler(service services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), principal.UserID, &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(r.Context(), principal.UserID)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).list(keys, len(keys)))
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RevokeAPIKey(r.Context(), principal.UserID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+// MultipartOverhead is how much a request body may exceed the attachment
+// size limit to leave room for multipart headers and boundaries.
+const MultipartOverhead = 64 << 10
+
+type AttachmentHandler struct {
+	service services.AttachmentService
+	maxSize int64
+}
+
+func NewAttachmentHandler(service services.AttachmentService, maxSize int64) *AttachmentHandler {
+	return &AttachmentHandler{service: service, maxSize: maxSize}
+}
+
+// UploadAttachment expects a multipart/form-data body with the file in a
+// part named "file". The file is streamed to storage, never buffered whole.
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+MultipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		problem.Error(w, "Expected a multipart/form-data body", http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			problem.Error(w, "Missing file part", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeAttachmentError(w, r, err)
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		attachment, err := h.service.Upload(r.Context(), taskID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			writeAttachmentError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(attachment)
+		return
+	}
+}
+
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	attachments, err := h.service.GetAttachments(r.Context(), taskID)
+	if err != nil {
+		writeAttachmentError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).list(attachments, len(attachments)))
+}
+
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, attachmentID, ok := parseTaskAttachmentIDs(w, r)
+	if !ok {
+		return
+	}
+
+	attachment, contents, err := h.service.Download(r.Context(), taskID, attachmentID)
+	if err != nil {
+		writeAttachmentError(w, r, err)
+		return
+	}
+	defer contents.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, contents); err != nil {
+		log.Printf("Failed to send attachment %d: %v", attachment.ID, err)
+	}
+}
+
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, attachmentID, ok := parseTaskAttachmentIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAttachment(r.Context(), taskID, attachmentID); err != nil {
+		writeAttachmentError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
+func parseTaskAttachmentIDs(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	attachmentID, err := strconv.ParseInt(vars["attachmentId"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return taskID, attachmentID, true
+}
+
+// writeAttachmentError also reports an upload cut off by the size limit as
+// too large.
+func writeAttachmentError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = services.ErrAttachmentTooLarge
+	}
+	writeError(w, r, err)
+}
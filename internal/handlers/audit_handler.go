@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditHandler struct {
+	service services.AuditService
+}
+
+func NewAuditHandler(service services.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+func (h *AuditHandler) GetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, offset := auditPage(r)
+
+	events, total, err := h.service.GetTaskHistory(r.Context(), taskID, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeAuditPage(w, r, page, limit, total, events)
+}
+
+// SearchEvents lists task events across all tasks, filtered by the optional
+// task_id, actor_id, action, from and to query parameters. from and to are
+// RFC 3339 times; to is exclusive.
+func (h *AuditHandler) SearchEvents(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	query := r.URL.Query()
+	filter := &models.EventFilter{Action: models.EventAction(query.Get("action"))}
+
+	var err error
+	if v := query.Get("task_id"); v != "" {
+		if filter.TaskID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			problem.Error(w, "Invalid task_id", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("actor_id"); v != "" {
+		if filter.ActorID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			problem.Error(w, "Invalid actor_id", http.StatusBadRequest)
+			return
+		}
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				problem.Error(w, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*dst = &t
+		}
+	}
+
+	page, limit, offset := auditPage(r)
+
+	events, total, err := h.service.SearchEvents(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeAuditPage(w, r, page, limit, total, events)
+}
+
+func auditPage(r *http.Request) (page, limit, offset int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err = strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	return page, limit, (page - 1) * limit
+}
+
+func writeAuditPage(w http.ResponseWriter, r *http.Request, page, limit, total int, events []*models.TaskEvent) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).offsetPage(events, page, limit, total))
+}
@@ -2,42 +2,141 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"os"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
 )
 
-var jwtSecret = func() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	// if secret == "" {
-	// 	log.Fatal("JWT_SECRET environment variable not set")
-	// }
-	return []byte(secret)
-}()
+type AuthHandler struct {
+	service services.UserService
+	tokens  services.TokenService
+}
+
+func NewAuthHandler(service services.UserService, tokens services.TokenService) *AuthHandler {
+	return &AuthHandler{service: service, tokens: tokens}
+}
+
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.Register(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(user)
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 
-	if username != "user" || password != "password" {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	user, err := h.service.Authenticate(r.Context(), username, password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokens, err := h.tokens.IssueTokens(r.Context(), user)
+	if err != nil {
+		problem.Error(w, "Could not create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(time.Hour * 24).Unix(),
-	})
+	tokens, err := h.tokens.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			problem.Write(w, problem.New(http.StatusUnauthorized, "invalid_token", err.Error()))
+		} else {
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The refresh token is optional; an empty body only revokes the access token.
+	var req models.RefreshRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err := h.tokens.Logout(r.Context(), principal, req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			problem.Write(w, problem.New(http.StatusBadRequest, "invalid_token", err.Error()))
+		} else {
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	principal, ok := auth.FromContext(r.Context())
+	if !ok {
+		problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
 
-	tokenString, err := token.SignedString(jwtSecret)
+	err := h.service.ChangePassword(r.Context(), principal.UserID, &req)
 	if err != nil {
-		http.Error(w, "Could not create token", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": tokenString})
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+)
+
+type BulkHandler struct {
+	service services.BulkService
+}
+
+func NewBulkHandler(service services.BulkService) *BulkHandler {
+	return &BulkHandler{service: service}
+}
+
+// ExecuteBulk runs a batch of task operations. A best-effort batch always
+// answers 200 with a result per operation. An atomic batch that fails
+// answers 422, or 500 if the failure was ours, with the same report.
+func (h *BulkHandler) ExecuteBulk(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.service.Execute(r.Context(), &req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if resp.Atomic && resp.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	for _, result := range resp.Results {
+		if result.Err == nil {
+			continue
+		}
+		p := errorProblem(r, result.Err)
+		result.Code, result.ErrorCode, result.Error, result.Errors = p.Status, p.Code, p.Detail, p.Errors
+		if resp.Atomic && p.Status == http.StatusInternalServerError {
+			status = http.StatusInternalServerError
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(viewOf(r).bulkResponse(resp))
+}
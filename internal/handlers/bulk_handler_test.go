@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/handlers"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBulkService struct {
+	mock.Mock
+}
+
+func (m *MockBulkService) Execute(ctx context.Context, req *models.BulkRequest) (*models.BulkResponse, error) {
+	args := m.Called(ctx, req)
+	if r := args.Get(0); r != nil {
+		return r.(*models.BulkResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestExecuteBulk(t *testing.T) {
+	serve := func(svc services.BulkService, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tasks/bulk", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		handlers.NewBulkHandler(svc).ExecuteBulk(rr, req)
+		return rr
+	}
+	report := func(atomic bool, errs ...error) *models.BulkResponse {
+		resp := &models.BulkResponse{Atomic: atomic}
+		for i, err := range errs {
+			result := &models.BulkResult{Index: i, Action: models.BulkComplete, ID: int64(i + 1), Status: models.BulkSucceeded}
+			if err != nil {
+				result.Status = models.BulkFailed
+				result.Err = err
+				resp.Failed++
+			} else {
+				resp.Succeeded++
+			}
+			resp.Results = append(resp.Results, result)
+		}
+		return resp
+	}
+
+	t.Run("best effort reports per-item errors", func(t *testing.T) {
+		mockSvc := new(MockBulkService)
+		mockSvc.On("Execute", mock.Anything, mock.MatchedBy(func(req *models.BulkRequest) bool {
+			return !req.Atomic && len(req.Operations) == 3 && req.Operations[1].ID == 2
+		})).Return(report(false, nil, services.ErrTaskNotFound, errors.New("db down")), nil).Once()
+
+		rr := serve(mockSvc, `{"operations":[{"action":"complete","id":1},{"action":"complete","id":2},{"action":"complete","id":3}]}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var res models.BulkResponse
+		json.Unmarshal(rr.Body.Bytes(), &res)
+		assert.Equal(t, 1, res.Succeeded)
+		assert.Equal(t, 2, res.Failed)
+		assert.Equal(t, http.StatusNotFound, res.Results[1].Code)
+		assert.Equal(t, services.ErrTaskNotFound.Error(), res.Results[1].Error)
+		assert.Equal(t, http.StatusInternalServerError, res.Results[2].Code)
+		assert.Equal(t, "Internal server error", res.Results[2].Error)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("failed atomic batch", func(t *testing.T) {
+		mockSvc := new(MockBulkService)
+		mockSvc.On("Execute", mock.Anything, mock.Anything).Return(report(true, nil, services.ErrVersionMismatch), nil).Once()
+
+		rr := serve(mockSvc, `{"atomic":true,"operations":[{"action":"complete","id":1},{"action":"complete","id":2}]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"code":412`)
+	})
+
+	t.Run("request errors", func(t *testing.T) {
+		testCases := map[string]struct {
+			err  error
+			code int
+		}{
+			"too large": {services.ErrBatchTooLarge, http.StatusRequestEntityTooLarge},
+			"empty":     {services.ErrInvalidInput, http.StatusBadRequest},
+		}
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockSvc := new(MockBulkService)
+				mockSvc.On("Execute", mock.Anything, mock.Anything).Return(nil, tc.err).Once()
+
+				rr := serve(mockSvc, `{"operations":[]}`)
+				assert.Equal(t, tc.code, rr.Code)
+			})
+		}
+
+		rr := serve(new(MockBulkService), `{"operations":`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+type DependencyHandler struct {
+	service services.DependencyService
+}
+
+func NewDependencyHandler(service services.DependencyService) *DependencyHandler {
+	return &DependencyHandler{service: service}
+}
+
+func (h *DependencyHandler) GetBlockers(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.service.GetBlockers(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).tasks(tasks))
+}
+
+func (h *DependencyHandler) AddDependency(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, blockerID, ok := parseDependencyIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.AddDependency(r.Context(), taskID, blockerID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
+func (h *DependencyHandler) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	taskID, blockerID, ok := parseDependencyIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RemoveDependency(r.Context(), taskID, blockerID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
+func (h *DependencyHandler) GetNextTasks(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = services.DefaultNextTasks
+	}
+	if limit > services.MaxNextTasks {
+		limit = services.MaxNextTasks
+	}
+
+	tasks, err := h.service.GetNextTasks(r.Context(), limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).tasks(tasks))
+}
+
+func parseDependencyIDs(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	vars := mux.Vars(r)
+	taskID, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	blockerID, err := strconv.ParseInt(vars["blockerId"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid blocking task ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	return taskID, blockerID, true
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+	"task-manager/internal/validation"
+)
+
+// serviceErrors maps the errors services return to problem responses. The
+// first entry an error matches wins, so specific errors come before the
+// general ones they wrap, such as ErrInvalidInput.
+var serviceErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{services.ErrTaskNotFound, http.StatusNotFound, "task_not_found"},
+	{services.ErrNotRecurring, http.StatusNotFound, "task_not_recurring"},
+	{services.ErrTagNotFound, http.StatusNotFound, "tag_not_found"},
+	{services.ErrCommentNotFound, http.StatusNotFound, "comment_not_found"},
+	{services.ErrAttachmentNotFound, http.StatusNotFound, "attachment_not_found"},
+	{services.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{services.ErrAPIKeyNotFound, http.StatusNotFound, "api_key_not_found"},
+	{services.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+	{services.ErrInvalidTransition, http.StatusConflict, "invalid_transition"},
+	{services.ErrParentCycle, http.StatusConflict, "parent_cycle"},
+	{services.ErrHasChildren, http.StatusConflict, "has_children"},
+	{services.ErrOpenChildren, http.StatusConflict, "open_children"},
+	{services.ErrTaskBlocked, http.StatusConflict, "task_blocked"},
+	{services.ErrDependencyCycle, http.StatusConflict, "dependency_cycle"},
+	{services.ErrPatchTestFailed, http.StatusConflict, "patch_test_failed"},
+	{services.ErrTagExists, http.StatusConflict, "tag_exists"},
+	{services.ErrUsernameTaken, http.StatusConflict, "username_taken"},
+	{services.ErrNoPassword, http.StatusConflict, "no_password"},
+	{services.ErrNotCommentAuthor, http.StatusForbidden, "not_comment_author"},
+	{services.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{services.ErrNoCaller, http.StatusUnauthorized, "unauthenticated"},
+	{services.ErrAttachmentTooLarge, http.StatusRequestEntityTooLarge, "attachment_too_large"},
+	{services.ErrBatchTooLarge, http.StatusRequestEntityTooLarge, "batch_too_large"},
+	{services.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{services.ErrInvalidParent, http.StatusBadRequest, "invalid_parent"},
+	{services.ErrInvalidDependency, http.StatusBadRequest, "invalid_dependency"},
+	{services.ErrInvalidPatch, http.StatusBadRequest, "invalid_patch"},
+	{services.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{services.ErrWeakPassword, http.StatusBadRequest, "weak_password"},
+	{services.ErrInvalidInput, http.StatusBadRequest, "invalid_input"},
+}
+
+// errorProblem returns the problem describing err. Errors no entry matches
+// are ours rather than the caller's, so their details are logged instead of
+// sent.
+func errorProblem(r *http.Request, err error) *problem.Problem {
+	var p *problem.Problem
+
+	var fields validation.Errors
+	if errors.As(err, &fields) {
+		p = problem.New(http.StatusBadRequest, "validation_failed", "The request has invalid fields")
+		p.Errors = fields
+	} else {
+		for _, e := range serviceErrors {
+			if errors.Is(err, e.err) {
+				p = problem.New(e.status, e.code, err.Error())
+				break
+			}
+		}
+	}
+
+	if p == nil {
+		log.Printf("Error handling %s %s: %v", r.Method, r.URL.Path, err)
+		p = problem.New(http.StatusInternalServerError, "internal_error", "Internal server error")
+	}
+	p.Instance = r.URL.Path
+	return p
+}
+
+// writeError answers a request that failed with err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(w, errorProblem(r, err))
+}
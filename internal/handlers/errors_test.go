@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"task-manager/internal/handlers"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+	"task-manager/internal/validation"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProblemResponses(t *testing.T) {
+	fields := validation.Errors{{Field: "title", Code: validation.CodeRequired, Message: "is required"}}
+
+	testCases := map[string]struct {
+		err    error
+		status int
+		code   string
+		detail string
+		fields []validation.FieldError
+	}{
+		"field errors": {
+			err:    fmt.Errorf("%w: %w", services.ErrInvalidInput, fields),
+			status: http.StatusBadRequest,
+			code:   "validation_failed",
+			detail: "The request has invalid fields",
+			fields: fields,
+		},
+		"service error": {
+			err:    fmt.Errorf("parent 3: %w", services.ErrInvalidParent),
+			status: http.StatusBadRequest,
+			code:   "invalid_parent",
+			detail: "parent 3: invalid parent task",
+		},
+		"missing caller": {
+			err:    services.ErrNoCaller,
+			status: http.StatusUnauthorized,
+			code:   "unauthenticated",
+			detail: "no authenticated caller in context",
+		},
+		"unknown error is not leaked": {
+			err:    errors.New("pq: connection refused"),
+			status: http.StatusInternalServerError,
+			code:   "internal_error",
+			detail: "Internal server error",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			mockSvc := new(MockTaskService)
+			handler := handlers.NewTaskHandler(mockSvc)
+			mockSvc.On("CreateTask", mock.Anything, mock.Anything).Return(nil, tc.err).Once()
+
+			rr := makeRequest(t, handler.CreateTask, "POST", "/tasks", &models.CreateTaskRequest{Title: "T", DueDate: time.Now()})
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+			var p problem.Problem
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.code, p.Code)
+			assert.Equal(t, tc.detail, p.Detail)
+			assert.Equal(t, tc.fields, p.Errors)
+			assert.Equal(t, "/tasks", p.Instance)
+		})
+	}
+
+	t.Run("malformed body", func(t *testing.T) {
+		handler := handlers.NewTaskHandler(new(MockTaskService))
+
+		rr := makeRequest(t, handler.CreateTask, "POST", "/tasks", "not an object")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var p problem.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+		assert.Equal(t, "bad_request", p.Code)
+		assert.Equal(t, "/problems/bad-request", p.Type)
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+)
+
+// taskETag is a strong entity tag for body, the representation of a task at
+// version. The version comes first so If-Match can check it; the digest
+// covers what the version does not count, such as tags, dependencies,
+// comments and the progress of subtasks, and tells the API versions apart.
+func taskETag(version int64, body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + strconv.FormatInt(version, 10) + "-" + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// issuedETag reports whether tag has the form taskETag writes. Weak tags
+// never match for If-Match, and neither do bare versions, so both are
+// rejected.
+func issuedETag(tag string) bool {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return false
+	}
+	digits, digest, ok := strings.Cut(tag[1:len(tag)-1], "-")
+	version, err := strconv.ParseInt(digits, 10, 64)
+	if !ok || err != nil || version < 1 || len(digest) != 16 {
+		return false
+	}
+	_, err = hex.DecodeString(digest)
+	return err == nil
+}
+
+// encodeTask renders task in the view of r and returns the body with its
+// entity tag.
+func encodeTask(r *http.Request, task *models.Task) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(viewOf(r).task(task)); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), taskETag(task.Version, buf.Bytes()), nil
+}
+
+// ifNoneMatch reports whether the If-None-Match header of r matches etag,
+// using the weak comparison RFC 9110 prescribes for it.
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion checks the If-Match header of r against the current
+// entity tag of task id and returns the version the write is then
+// conditional on. It returns 0 if there is no condition, and writes the
+// response and returns false if the request cannot proceed: 428 when the
+// header is required but missing, 412 when it does not match. The write
+// itself only rechecks the version, so a change to details the version does
+// not count that lands between the two checks goes unnoticed.
+func (h *TaskHandler) ifMatchVersion(w http.ResponseWriter, r *http.Request, id int64) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		if h.requireIfMatch {
+			problem.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+			return 0, false
+		}
+		return 0, true
+	}
+	if header == "*" {
+		return 0, true
+	}
+
+	// Only one version can be current, so a list of several can match at
+	// most one of them; requiring a single tag keeps the check atomic.
+	if !issuedETag(header) {
+		problem.Write(w, problem.New(http.StatusPreconditionFailed, "version_mismatch", "Precondition failed"))
+		return 0, false
+	}
+
+	task, err := h.service.GetTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return 0, false
+	}
+	_, etag, err := encodeTask(r, task)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return 0, false
+	}
+	if header != etag {
+		problem.Write(w, problem.New(http.StatusPreconditionFailed, "version_mismatch", "Precondition failed"))
+		return 0, false
+	}
+	return task.Version, true
+}
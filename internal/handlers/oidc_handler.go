@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
+	"time"
+)
+
+type OIDCHandler struct {
+	service services.OIDCService
+}
+
+func NewOIDCHandler(service services.OIDCService) *OIDCHandler {
+	return &OIDCHandler{service: service}
+}
+
+// oidcStateCookie carries the state of a login to the callback, which only
+// accepts the state of the browser that started it. Without it, a victim
+// could be sent a callback URL for the attacker's account and logged in as
+// the attacker.
+const oidcStateCookie = "oidc_state"
+
+// Login redirects the browser to the identity provider.
+func (h *OIDCHandler) Login(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	authURL, state, err := h.service.BeginLogin(r.Context())
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// SameSite=Lax still sends the cookie on the provider's top-level
+	// redirect back to the callback.
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path.Dir(r.URL.Path),
+		MaxAge:   int(services.OIDCLoginTTL / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback is the redirect target registered with the identity provider. It
+// responds with the same token pair as /login.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	q := r.URL.Query()
+	if errCode := q.Get("error"); errCode != "" {
+		log.Printf("OIDC provider returned error: %s %s", errCode, q.Get("error_description"))
+		problem.Write(w, problem.New(http.StatusUnauthorized, "oidc_login_failed", services.ErrOIDCLoginFailed.Error()))
+		return
+	}
+
+	state, code := q.Get("state"), q.Get("code")
+	if state == "" || code == "" {
+		problem.Error(w, "Missing state or code", http.StatusBadRequest)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: path.Dir(r.URL.Path), MaxAge: -1, HttpOnly: true})
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		log.Printf("OIDC callback state does not match the browser's login")
+		problem.Write(w, problem.New(http.StatusUnauthorized, "oidc_login_failed", services.ErrOIDCLoginFailed.Error()))
+		return
+	}
+
+	tokens, err := h.service.CompleteLogin(r.Context(), state, code)
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCLoginFailed) {
+			problem.Write(w, problem.New(http.StatusUnauthorized, "oidc_login_failed", err.Error()))
+		} else {
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
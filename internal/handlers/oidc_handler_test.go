@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/handlers"
+	"task-manager/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) BeginLogin(ctx context.Context) (string, string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, state, code string) (*models.TokenPair, error) {
+	args := m.Called(ctx, state, code)
+	if p := args.Get(0); p != nil {
+		return p.(*models.TokenPair), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestOIDCStateBinding(t *testing.T) {
+	t.Run("Login sets the state cookie", func(t *testing.T) {
+		mockSvc := new(MockOIDCService)
+		mockSvc.On("BeginLogin", mock.Anything).Return("https://idp.example.com/auth?state=s1", "s1", nil).Once()
+
+		rr := httptest.NewRecorder()
+		handlers.NewOIDCHandler(mockSvc).Login(rr, httptest.NewRequest("GET", "/v1/oidc/login", nil))
+
+		assert.Equal(t, http.StatusFound, rr.Code)
+		cookies := rr.Result().Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, "s1", cookies[0].Value)
+			assert.Equal(t, "/v1/oidc", cookies[0].Path)
+			assert.True(t, cookies[0].HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+		}
+	})
+
+	testCases := map[string]struct {
+		cookie string
+		status int
+	}{
+		"matching cookie": {cookie: "s1", status: http.StatusOK},
+		"missing cookie":  {status: http.StatusUnauthorized},
+		"someone else's":  {cookie: "s2", status: http.StatusUnauthorized},
+	}
+
+	for name, tc := range testCases {
+		t.Run("Callback with "+name, func(t *testing.T) {
+			mockSvc := new(MockOIDCService)
+			if tc.status == http.StatusOK {
+				mockSvc.On("CompleteLogin", mock.Anything, "s1", "c1").Return(&models.TokenPair{}, nil).Once()
+			}
+
+			req := httptest.NewRequest("GET", "/v1/oidc/callback?state=s1&code=c1", nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "oidc_state", Value: tc.cookie})
+			}
+			rr := httptest.NewRecorder()
+			handlers.NewOIDCHandler(mockSvc).Callback(rr, req)
+
+			assert.Equal(t, tc.status, rr.Code)
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
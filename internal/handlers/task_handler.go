@@ -2,23 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"task-manager/internal/models"
-	"task-manager/internal/repository"
+	"task-manager/internal/problem"
 	"task-manager/internal/services"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
 type TaskHandler struct {
-	service services.TaskService
+	service        services.TaskService
+	requireIfMatch bool
 }
 
-func NewTaskHandler(service services.TaskService) *TaskHandler {
-	return &TaskHandler{service: service}
+type TaskHandlerOption func(*TaskHandler)
+
+// RequireIfMatch makes writes to an existing task fail with 428 unless they
+// carry an If-Match header, so clients cannot overwrite changes they have
+// not seen.
+func RequireIfMatch(required bool) TaskHandlerOption {
+	return func(h *TaskHandler) {
+		h.requireIfMatch = required
+	}
+}
+
+func NewTaskHandler(service services.TaskService, opts ...TaskHandlerOption) *TaskHandler {
+	h := &TaskHandler{service: service}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
@@ -26,23 +47,19 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	var req models.CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	task, err := h.service.CreateTask(r.Context(), &req)
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidInput) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(task)
+	json.NewEncoder(w).Encode(viewOf(r).task(task))
 }
 
 // wrote full UT for GetTask handler
@@ -53,22 +70,29 @@ func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
 	task, err := h.service.GetTask(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, services.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
+		return
+	}
+
+	body, etag, err := encodeTask(r, task)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(task)
+	w.Write(body)
 }
 
 func (h *TaskHandler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
@@ -86,22 +110,130 @@ func (h *TaskHandler) GetAllTasks(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * limit
 
-	tasks, total, err := h.service.GetAllTasks(r.Context(), limit, offset)
+	filter, err := parseTaskFilter(r.URL.Query())
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		problem.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	response := map[string]interface{}{
-		"page":       page,
-		"limit":      limit,
-		"total":      total,
-		"totalPages": (int(total) + limit - 1) / limit,
-		"data":       tasks,
+	// Passing cursor, even empty for the first page, switches to keyset
+	// pagination, which ignores page.
+	if r.URL.Query().Has("cursor") {
+		h.listTasks(w, r, filter, limit)
+		return
+	}
+
+	tasks, total, err := h.service.GetAllTasks(r.Context(), filter, limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	view := viewOf(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view.offsetPage(view.tasks(tasks), page, limit, total))
+}
+
+func (h *TaskHandler) listTasks(w http.ResponseWriter, r *http.Request, filter *models.TaskFilter, limit int) {
+	query := r.URL.Query()
+
+	withTotal := false
+	if v := query.Get("total"); v != "" {
+		var err error
+		if withTotal, err = strconv.ParseBool(v); err != nil {
+			problem.Error(w, "Invalid total", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.service.ListTasks(r.Context(), filter, query.Get("cursor"), limit, withTotal)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(viewOf(r).cursorPage(page))
+}
+
+// parseTaskFilter reads the GET /tasks query parameters:
+//
+//	completed=true|false
+//	overdue=true
+//	due_from, due_to, created_from, created_to, updated_from, updated_to (RFC 3339)
+//	priority=high,urgent   (any of, repeatable)
+//	tag=work,home          (all of, repeatable)
+//	sort=created_at|updated_at|due_date|priority|status|title
+//	order=asc|desc
+//
+// Without sort, tasks are listed newest first; with it, order defaults to asc.
+func parseTaskFilter(query url.Values) (*models.TaskFilter, error) {
+	filter := &models.TaskFilter{}
+
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid completed %q", v)
+		}
+		filter.Completed = &completed
+	}
+	if v := query.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid overdue %q", v)
+		}
+		filter.Overdue = overdue
+	}
+
+	times := map[string]**time.Time{
+		"due_from":     &filter.DueFrom,
+		"due_to":       &filter.DueTo,
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"updated_from": &filter.UpdatedFrom,
+		"updated_to":   &filter.UpdatedTo,
+	}
+	for name, dst := range times {
+		if v := query.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q, expected RFC 3339", name, v)
+			}
+			*dst = &t
+		}
+	}
+
+	for _, p := range listParam(query, "priority") {
+		filter.Priorities = append(filter.Priorities, models.Priority(p))
+	}
+	filter.Tags = listParam(query, "tag")
+
+	filter.SortBy = models.TaskSortField(query.Get("sort"))
+	switch order := query.Get("order"); order {
+	case "":
+		filter.Descending = filter.SortBy == ""
+	case "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return nil, fmt.Errorf("invalid order %q", order)
+	}
+
+	return filter, nil
+}
+
+// listParam collects the values of a query parameter that may be repeated
+// and may hold a comma-separated list.
+func listParam(query url.Values, name string) []string {
+	var values []string
+	for _, v := range query[name] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
 }
 
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
@@ -110,28 +242,86 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	version, ok := h.ifMatchVersion(w, r, id)
+	if !ok {
 		return
 	}
 
 	var req models.UpdateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	task, err := h.service.UpdateTask(r.Context(), id, &req)
+	task, err := h.service.UpdateTask(r.Context(), id, &req, version)
 	if err != nil {
-		if errors.Is(err, services.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 
+	body, etag, err := encodeTask(r, task)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(task)
+	w.Write(body)
+}
+
+// maxPatchSize bounds a PATCH body; a task's editable fields are far
+// smaller.
+const maxPatchSize = 1 << 20
+
+// PatchTask changes some of a task's fields. The body is a JSON Merge Patch
+// or a JSON Patch, told apart by its Content-Type.
+func (h *TaskHandler) PatchTask(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format := models.PatchFormat(mediaType)
+	if format != models.MergePatch && format != models.JSONPatch {
+		w.Header().Set("Accept-Patch", string(models.MergePatch)+", "+string(models.JSONPatch))
+		problem.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	version, ok := h.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		problem.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.service.PatchTask(r.Context(), id, patch, format, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	body, etag, err := encodeTask(r, task)
+	if err != nil {
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
 }
 
 func (h *TaskHandler) MarkTaskComplete(w http.ResponseWriter, r *http.Request) {
@@ -140,17 +330,18 @@ func (h *TaskHandler) MarkTaskComplete(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.MarkTaskComplete(r.Context(), id)
+	version, ok := h.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
+
+	err = h.service.MarkTaskComplete(r.Context(), id, version)
 	if err != nil {
-		if errors.Is(err, repository.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -163,23 +354,184 @@ func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.ParseInt(vars["id"], 10, 64)
 	if err != nil {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.service.DeleteTask(r.Context(), id)
+	version, ok := h.ifMatchVersion(w, r, id)
+	if !ok {
+		return
+	}
+
+	err = h.service.DeleteTask(r.Context(), id, version)
 	if err != nil {
-		if errors.Is(err, repository.ErrTaskNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
 }
 
+func (h *TaskHandler) SearchTasks(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	results, total, err := h.service.SearchTasks(r.Context(), r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	view := viewOf(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view.offsetPage(view.searchResults(results), page, limit, total))
+}
+
+func (h *TaskHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	tasks, total, err := h.service.GetTrash(r.Context(), limit, offset)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	view := viewOf(r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view.offsetPage(view.tasks(tasks), page, limit, total))
+}
+
+func (h *TaskHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.RestoreTask(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
+func (h *TaskHandler) GetTaskChildren(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.service.GetChildren(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).tasks(tasks))
+}
+
+func (h *TaskHandler) GetTaskSubtree(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	task, err := h.service.GetSubtree(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(viewOf(r).task(task))
+}
+
+func (h *TaskHandler) PreviewOccurrences(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 1 {
+		count = services.DefaultOccurrencePreview
+	}
+	if count > services.MaxOccurrencePreview {
+		count = services.MaxOccurrencePreview
+	}
+
+	resp, err := h.service.PreviewOccurrences(r.Context(), id, count)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *TaskHandler) StopRecurrence(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Handler triggered: %s %s", r.Method, r.URL.Path)
+
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		problem.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.StopRecurrence(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(setResponseMessageStatus(true))
+}
+
 func setResponseMessageStatus(status bool) map[string]bool {
 	return map[string]bool{"status": status}
 }
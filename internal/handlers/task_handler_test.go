@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"task-manager/internal/handlers"
@@ -38,16 +39,32 @@ func (m *MockTaskService) GetTask(ctx context.Context, id int64) (*models.Task,
 	return nil, args.Error(1)
 }
 
-func (m *MockTaskService) GetAllTasks(ctx context.Context, limit int, offset int) ([]*models.Task, int, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *MockTaskService) GetAllTasks(ctx context.Context, filter *models.TaskFilter, limit int, offset int) ([]*models.Task, int, error) {
+	args := m.Called(ctx, filter, limit, offset)
 	if t := args.Get(0); t != nil {
 		return t.([]*models.Task), args.Int(1), args.Error(2)
 	}
 	return nil, 0, args.Error(2)
 }
 
-func (m *MockTaskService) UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest) (*models.Task, error) {
-	args := m.Called(ctx, id, req)
+func (m *MockTaskService) ListTasks(ctx context.Context, filter *models.TaskFilter, cursor string, limit int, withTotal bool) (*models.TaskPage, error) {
+	args := m.Called(ctx, filter, cursor, limit, withTotal)
+	if p := args.Get(0); p != nil {
+		return p.(*models.TaskPage), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest, version int64) (*models.Task, error) {
+	args := m.Called(ctx, id, req, version)
+	if t := args.Get(0); t != nil {
+		return t.(*models.Task), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) PatchTask(ctx context.Context, id int64, patch []byte, format models.PatchFormat, version int64) (*models.Task, error) {
+	args := m.Called(ctx, id, patch, format, version)
 	if t := args.Get(0); t != nil {
 		return t.(*models.Task), args.Error(1)
 	}
@@ -62,11 +79,59 @@ func (m *MockTaskService) GetDueTasks(ctx context.Context, from int64, to int64)
 	return nil, args.Error(1)
 }
 
-func (m *MockTaskService) MarkTaskComplete(ctx context.Context, id int64) error {
+func (m *MockTaskService) MarkTaskComplete(ctx context.Context, id, version int64) error {
+	return m.Called(ctx, id, version).Error(0)
+}
+
+func (m *MockTaskService) DeleteTask(ctx context.Context, id, version int64) error {
+	return m.Called(ctx, id, version).Error(0)
+}
+
+func (m *MockTaskService) GetChildren(ctx context.Context, id int64) ([]*models.Task, error) {
+	args := m.Called(ctx, id)
+	if t := args.Get(0); t != nil {
+		return t.([]*models.Task), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetSubtree(ctx context.Context, id int64) (*models.Task, error) {
+	args := m.Called(ctx, id)
+	if t := args.Get(0); t != nil {
+		return t.(*models.Task), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) PreviewOccurrences(ctx context.Context, id int64, n int) (*models.OccurrencesResponse, error) {
+	args := m.Called(ctx, id, n)
+	if r := args.Get(0); r != nil {
+		return r.(*models.OccurrencesResponse), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) StopRecurrence(ctx context.Context, id int64) error {
 	return m.Called(ctx, id).Error(0)
 }
 
-func (m *MockTaskService) DeleteTask(ctx context.Context, id int64) error {
+func (m *MockTaskService) SearchTasks(ctx context.Context, query string, limit, offset int) ([]*models.TaskSearchResult, int, error) {
+	args := m.Called(ctx, query, limit, offset)
+	if r := args.Get(0); r != nil {
+		return r.([]*models.TaskSearchResult), args.Int(1), args.Error(2)
+	}
+	return nil, 0, args.Error(2)
+}
+
+func (m *MockTaskService) GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+	args := m.Called(ctx, limit, offset)
+	if t := args.Get(0); t != nil {
+		return t.([]*models.Task), args.Int(1), args.Error(2)
+	}
+	return nil, 0, args.Error(2)
+}
+
+func (m *MockTaskService) RestoreTask(ctx context.Context, id int64) error {
 	return m.Called(ctx, id).Error(0)
 }
 
@@ -94,7 +159,7 @@ func TestCreateTask(t *testing.T) {
 		Description: "Desc 1",
 		DueDate:     time.Now(),
 	}
-	expected := &models.Task{ID: 1, Title: reqBody.Title, Description: reqBody.Description, DueDate: reqBody.DueDate}
+	expected := &models.Task{ID: 1, Title: reqBody.Title, Description: reqBody.Description, DueDate: &reqBody.DueDate}
 
 	mockSvc.On("CreateTask", mock.Anything, mock.MatchedBy(func(req *models.CreateTaskRequest) bool {
 		return req.Title == "Task 1" && req.Description == "Desc 1"
@@ -186,7 +251,7 @@ func TestGetAllTasks(t *testing.T) {
 	tasks := []*models.Task{{ID: 1}, {ID: 2}}
 	total := 2
 
-	mockSvc.On("GetAllTasks", mock.Anything, 10, 0).Return(tasks, total, nil)
+	mockSvc.On("GetAllTasks", mock.Anything, &models.TaskFilter{Descending: true}, 10, 0).Return(tasks, total, nil)
 
 	req := httptest.NewRequest("GET", "/tasks?page=1&limit=10", nil)
 	rr := httptest.NewRecorder()
@@ -205,6 +270,95 @@ func TestGetAllTasks(t *testing.T) {
 	mockSvc.AssertExpectations(t)
 }
 
+func TestGetAllTasksFilters(t *testing.T) {
+	dueFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	dueTo := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	completed := false
+
+	t.Run("parses filters", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+
+		want := &models.TaskFilter{
+			Completed:  &completed,
+			Overdue:    true,
+			DueFrom:    &dueFrom,
+			DueTo:      &dueTo,
+			Priorities: []models.Priority{models.PriorityHigh, models.PriorityUrgent},
+			Tags:       []string{"work", "home"},
+			SortBy:     models.SortByDueDate,
+		}
+		mockSvc.On("GetAllTasks", mock.Anything, want, 10, 0).Return([]*models.Task{}, 0, nil).Once()
+
+		req := httptest.NewRequest("GET", "/tasks?completed=false&overdue=true&due_from=2025-01-01T00:00:00Z&due_to=2025-02-01T00:00:00Z&priority=high,urgent&tag=work&tag=home&sort=due_date", nil)
+		rr := httptest.NewRecorder()
+		handler.GetAllTasks(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		for name, query := range map[string]string{
+			"completed": "completed=maybe",
+			"due_from":  "due_from=yesterday",
+			"order":     "sort=title&order=sideways",
+		} {
+			t.Run(name, func(t *testing.T) {
+				handler := handlers.NewTaskHandler(new(MockTaskService))
+
+				req := httptest.NewRequest("GET", "/tasks?"+query, nil)
+				rr := httptest.NewRecorder()
+				handler.GetAllTasks(rr, req)
+
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+			})
+		}
+	})
+
+	t.Run("invalid filter from service", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+
+		mockSvc.On("GetAllTasks", mock.Anything, mock.Anything, 10, 0).Return(nil, 0, services.ErrInvalidInput).Once()
+
+		req := httptest.NewRequest("GET", "/tasks?sort=owner_id", nil)
+		rr := httptest.NewRecorder()
+		handler.GetAllTasks(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestGetAllTasksCursor(t *testing.T) {
+	mockSvc := new(MockTaskService)
+	handler := handlers.NewTaskHandler(mockSvc)
+
+	total := 7
+	page := &models.TaskPage{Tasks: []*models.Task{{ID: 3}, {ID: 2}}, NextCursor: "abc", Total: &total}
+	mockSvc.On("ListTasks", mock.Anything, &models.TaskFilter{Descending: true}, "", 2, true).Return(page, nil).Once()
+	mockSvc.On("ListTasks", mock.Anything, &models.TaskFilter{Descending: true}, "bad", 2, false).Return(nil, services.ErrInvalidCursor).Once()
+
+	req := httptest.NewRequest("GET", "/tasks?cursor=&limit=2&total=true", nil)
+	rr := httptest.NewRecorder()
+	handler.GetAllTasks(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var res map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	assert.Equal(t, "abc", res["next_cursor"])
+	assert.NotContains(t, res, "prev_cursor")
+	assert.Equal(t, float64(total), res["total"])
+	assert.Len(t, res["data"].([]interface{}), 2)
+
+	req = httptest.NewRequest("GET", "/tasks?cursor=bad&limit=2", nil)
+	rr = httptest.NewRecorder()
+	handler.GetAllTasks(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockSvc.AssertExpectations(t)
+}
+
 func TestUpdateTask(t *testing.T) {
 	mockSvc := new(MockTaskService)
 	handler := handlers.NewTaskHandler(mockSvc)
@@ -212,7 +366,7 @@ func TestUpdateTask(t *testing.T) {
 	reqBody := &models.UpdateTaskRequest{Title: "Updated"}
 	updated := &models.Task{ID: 1, Title: "Updated"}
 
-	mockSvc.On("UpdateTask", mock.Anything, int64(1), reqBody).Return(updated, nil)
+	mockSvc.On("UpdateTask", mock.Anything, int64(1), reqBody, int64(0)).Return(updated, nil)
 
 	req := httptest.NewRequest("PUT", "/tasks/1", bytes.NewBufferString(`{"title":"Updated"}`))
 	req.Header.Set("Content-Type", "application/json")
@@ -233,7 +387,7 @@ func TestMarkTaskComplete(t *testing.T) {
 	mockSvc := new(MockTaskService)
 	handler := handlers.NewTaskHandler(mockSvc)
 
-	mockSvc.On("MarkTaskComplete", mock.Anything, int64(1)).Return(nil)
+	mockSvc.On("MarkTaskComplete", mock.Anything, int64(1), int64(0)).Return(nil)
 
 	req := httptest.NewRequest("PATCH", "/tasks/1/complete", nil)
 	rr := httptest.NewRecorder()
@@ -252,7 +406,7 @@ func TestDeleteTask(t *testing.T) {
 	mockSvc := new(MockTaskService)
 	handler := handlers.NewTaskHandler(mockSvc)
 
-	mockSvc.On("DeleteTask", mock.Anything, int64(1)).Return(nil)
+	mockSvc.On("DeleteTask", mock.Anything, int64(1), int64(0)).Return(nil)
 
 	req := httptest.NewRequest("DELETE", "/tasks/1", nil)
 	rr := httptest.NewRecorder()
@@ -266,3 +420,191 @@ func TestDeleteTask(t *testing.T) {
 	assert.True(t, res["status"])
 	mockSvc.AssertExpectations(t)
 }
+
+func TestTaskPreconditions(t *testing.T) {
+	serve := func(handler *handlers.TaskHandler, method, url string, header http.Header, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/tasks/{id}", handler.GetTask).Methods("GET")
+		router.HandleFunc("/tasks/{id}", handler.UpdateTask).Methods("PUT")
+		router.HandleFunc("/tasks/{id}", handler.DeleteTask).Methods("DELETE")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("GetTask returns ETag and honours If-None-Match", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3}, nil)
+
+		rr := serve(handler, "GET", "/tasks/1", nil, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		etag := rr.Header().Get("ETag")
+		assert.Regexp(t, `^"3-[0-9a-f]{16}"$`, etag)
+
+		rr = serve(handler, "GET", "/tasks/1", http.Header{"If-None-Match": {`"2", W/` + etag}}, "")
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+
+		rr = serve(handler, "GET", "/tasks/1", http.Header{"If-None-Match": {`"3"`}}, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("ETag changes with details the version does not count", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3}, nil).Once()
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3, CommentCount: 1}, nil).Once()
+
+		before := serve(handler, "GET", "/tasks/1", nil, "").Header().Get("ETag")
+		rr := serve(handler, "GET", "/tasks/1", http.Header{"If-None-Match": {before}}, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.NotEqual(t, before, rr.Header().Get("ETag"))
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTask passes the version of a matching If-Match", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3}, nil)
+		mockSvc.On("UpdateTask", mock.Anything, int64(1), mock.Anything, int64(3)).Return(&models.Task{ID: 1, Version: 4}, nil).Once()
+
+		etag := serve(handler, "GET", "/tasks/1", nil, "").Header().Get("ETag")
+		rr := serve(handler, "PUT", "/tasks/1", http.Header{"If-Match": {etag}}, `{"title":"x"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `^"4-`, rr.Header().Get("ETag"))
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("If-Match compares the whole entity tag", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3}, nil).Once()
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3, CommentCount: 1}, nil)
+
+		// The version is still current but the comment count has moved on.
+		etag := serve(handler, "GET", "/tasks/1", nil, "").Header().Get("ETag")
+		for _, tag := range []string{etag, `"2-0123456789abcdef"`} {
+			rr := serve(handler, "PUT", "/tasks/1", http.Header{"If-Match": {tag}}, `{"title":"x"}`)
+			assert.Equal(t, http.StatusPreconditionFailed, rr.Code, tag)
+		}
+		mockSvc.AssertNotCalled(t, "UpdateTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("If-Match on a missing task", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(nil, services.ErrTaskNotFound).Once()
+
+		rr := serve(handler, "DELETE", "/tasks/1", http.Header{"If-Match": {`"3-0123456789abcdef"`}}, "")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("If-Match that can never match", func(t *testing.T) {
+		handler := handlers.NewTaskHandler(new(MockTaskService))
+
+		for _, tag := range []string{`W/"3-0123456789abcdef"`, `3`, `"3"`, `"3-0123"`, `"3-0123456789abcdef", "4-0123456789abcdef"`} {
+			rr := serve(handler, "DELETE", "/tasks/1", http.Header{"If-Match": {tag}}, "")
+			assert.Equal(t, http.StatusPreconditionFailed, rr.Code, tag)
+		}
+	})
+
+	t.Run("If-Match required", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc, handlers.RequireIfMatch(true))
+		mockSvc.On("DeleteTask", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+
+		rr := serve(handler, "DELETE", "/tasks/1", nil, "")
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+
+		rr = serve(handler, "DELETE", "/tasks/1", http.Header{"If-Match": {"*"}}, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		mockSvc.AssertExpectations(t)
+	})
+}
+
+// currentETag is the entity tag GetTask sends for task.
+func currentETag(task *models.Task) string {
+	mockSvc := new(MockTaskService)
+	mockSvc.On("GetTask", mock.Anything, task.ID).Return(task, nil)
+	rr := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/tasks/{id}", handlers.NewTaskHandler(mockSvc).GetTask)
+	router.ServeHTTP(rr, httptest.NewRequest("GET", fmt.Sprintf("/tasks/%d", task.ID), nil))
+	return rr.Header().Get("ETag")
+}
+
+func TestPatchTask(t *testing.T) {
+	serve := func(handler *handlers.TaskHandler, contentType, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/tasks/1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.HandleFunc("/tasks/{id}", handler.PatchTask).Methods("PATCH")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("picks the format from Content-Type", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		handler := handlers.NewTaskHandler(mockSvc)
+		merge := `{"title":"x"}`
+		ops := `[{"op":"replace","path":"/title","value":"x"}]`
+		mockSvc.On("GetTask", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Version: 3}, nil).Once()
+		mockSvc.On("PatchTask", mock.Anything, int64(1), []byte(merge), models.MergePatch, int64(3)).
+			Return(&models.Task{ID: 1, Title: "x", Version: 4}, nil).Once()
+		mockSvc.On("PatchTask", mock.Anything, int64(1), []byte(ops), models.JSONPatch, int64(0)).
+			Return(&models.Task{ID: 1, Title: "x", Version: 5}, nil).Once()
+
+		rr := serve(handler, "application/merge-patch+json; charset=utf-8", currentETag(&models.Task{ID: 1, Version: 3}), merge)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `^"4-`, rr.Header().Get("ETag"))
+
+		rr = serve(handler, "application/json-patch+json", "", ops)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `^"5-`, rr.Header().Get("ETag"))
+		mockSvc.AssertExpectations(t)
+	})
+
+	t.Run("unsupported Content-Type", func(t *testing.T) {
+		handler := handlers.NewTaskHandler(new(MockTaskService))
+
+		for _, contentType := range []string{"application/json", "", "text/plain"} {
+			rr := serve(handler, contentType, "", `{}`)
+			assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code, contentType)
+			assert.Contains(t, rr.Header().Get("Accept-Patch"), "application/merge-patch+json")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		testCases := map[string]struct {
+			err  error
+			code int
+		}{
+			"invalid patch":  {services.ErrInvalidPatch, http.StatusBadRequest},
+			"invalid result": {services.ErrInvalidInput, http.StatusBadRequest},
+			"failed test":    {services.ErrPatchTestFailed, http.StatusConflict},
+			"stale version":  {services.ErrVersionMismatch, http.StatusPreconditionFailed},
+			"not found":      {services.ErrTaskNotFound, http.StatusNotFound},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockSvc := new(MockTaskService)
+				handler := handlers.NewTaskHandler(mockSvc)
+				mockSvc.On("PatchTask", mock.Anything, int64(1), mock.Anything, models.JSONPatch, int64(0)).Return(nil, tc.err).Once()
+
+				rr := serve(handler, "application/json-patch+json", "", `[]`)
+				assert.Equal(t, tc.code, rr.Code)
+			})
+		}
+	})
+}
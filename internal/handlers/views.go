@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+	"task-manager/internal/apiversion"
+	"task-manager/internal/models"
+)
+
+// taskView renders tasks and listings in the shape of one API version.
+// Handlers are shared by every mounted version and leave the shape of the
+// tasks and listings they send to the view of the version the request was
+// made to.
+type taskView interface {
+	task(task *models.Task) interface{}
+	tasks(tasks []*models.Task) interface{}
+	searchResults(results []*models.TaskSearchResult) interface{}
+	bulkResponse(resp *models.BulkResponse) interface{}
+	// offsetPage wraps one page of a page and limit listing.
+	offsetPage(data interface{}, page, limit, total int) interface{}
+	// cursorPage wraps one page of a keyset listing.
+	cursorPage(page *models.TaskPage) interface{}
+	// list wraps a listing that is sent whole, of total items.
+	list(data interface{}, total int) interface{}
+}
+
+func viewOf(r *http.Request) taskView {
+	if apiversion.FromContext(r.Context()) == apiversion.V2 {
+		return v2View{}
+	}
+	return v1View{}
+}
+
+// v1View sends the models as they are.
+type v1View struct{}
+
+func (v1View) task(task *models.Task) interface{} { return task }
+
+func (v1View) tasks(tasks []*models.Task) interface{} { return tasks }
+
+func (v1View) searchResults(results []*models.TaskSearchResult) interface{} { return results }
+
+func (v1View) bulkResponse(resp *models.BulkResponse) interface{} { return resp }
+
+func (v1View) offsetPage(data interface{}, page, limit, total int) interface{} {
+	return map[string]interface{}{
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + limit - 1) / limit,
+		"data":       data,
+	}
+}
+
+func (v1View) cursorPage(page *models.TaskPage) interface{} { return page }
+
+func (v1View) list(data interface{}, total int) interface{} {
+	return map[string]interface{}{"data": data}
+}
+
+// v2View drops what v1 keeps for old clients and moves listing metadata out
+// of the way of the data.
+type v2View struct{}
+
+// taskV2 is a task without is_completed, which v1 derives from status for
+// clients that predate it. The fields declared here shadow those of the
+// embedded task with the same JSON names: a nil IsCompleted leaves the
+// field out and Children nests v2 tasks.
+type taskV2 struct {
+	*models.Task
+	IsCompleted *bool     `json:"is_completed,omitempty"`
+	Children    []*taskV2 `json:"children,omitempty"`
+}
+
+type searchResultV2 struct {
+	*models.TaskSearchResult
+	Task *taskV2 `json:"task"`
+}
+
+type bulkResultV2 struct {
+	*models.BulkResult
+	Task *taskV2 `json:"task,omitempty"`
+}
+
+type bulkResponseV2 struct {
+	*models.BulkResponse
+	Results []*bulkResultV2 `json:"results"`
+}
+
+// listV2 is a listing: the items and, in Meta, how to get the rest.
+type listV2 struct {
+	Data interface{} `json:"data"`
+	Meta interface{} `json:"meta"`
+}
+
+type offsetMetaV2 struct {
+	Page       int `json:"page"`
+	Limit      int `json:"limit"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+type listMetaV2 struct {
+	Total int `json:"total"`
+}
+
+type cursorMetaV2 struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+func (v2View) task(task *models.Task) interface{} {
+	return newTaskV2(task)
+}
+
+func newTaskV2(task *models.Task) *taskV2 {
+	if task == nil {
+		return nil
+	}
+	out := &taskV2{Task: task}
+	for _, child := range task.Children {
+		out.Children = append(out.Children, newTaskV2(child))
+	}
+	return out
+}
+
+func (v2View) tasks(tasks []*models.Task) interface{} {
+	out := make([]*taskV2, 0, len(tasks))
+	for _, task := range tasks {
+		out = append(out, newTaskV2(task))
+	}
+	return out
+}
+
+func (v2View) searchResults(results []*models.TaskSearchResult) interface{} {
+	out := make([]*searchResultV2, 0, len(results))
+	for _, result := range results {
+		out = append(out, &searchResultV2{TaskSearchResult: result, Task: newTaskV2(result.Task)})
+	}
+	return out
+}
+
+func (v2View) bulkResponse(resp *models.BulkResponse) interface{} {
+	out := &bulkResponseV2{BulkResponse: resp, Results: make([]*bulkResultV2, 0, len(resp.Results))}
+	for _, result := range resp.Results {
+		out.Results = append(out.Results, &bulkResultV2{BulkResult: result, Task: newTaskV2(result.Task)})
+	}
+	return out
+}
+
+func (v2View) offsetPage(data interface{}, page, limit, total int) interface{} {
+	return listV2{
+		Data: data,
+		Meta: offsetMetaV2{Page: page, Limit: limit, Total: total, TotalPages: (total + limit - 1) / limit},
+	}
+}
+
+func (v v2View) cursorPage(page *models.TaskPage) interface{} {
+	return listV2{
+		Data: v.tasks(page.Tasks),
+		Meta: cursorMetaV2{NextCursor: page.NextCursor, PrevCursor: page.PrevCursor, Total: page.Total},
+	}
+}
+
+func (v2View) list(data interface{}, total int) interface{} {
+	return listV2{Data: data, Meta: listMetaV2{Total: total}}
+}
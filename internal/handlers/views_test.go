@@ -0,0 +1,114 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/apiversion"
+	"task-manager/internal/auth"
+	"task-manager/internal/handlers"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskViews(t *testing.T) {
+	subtree := &models.Task{
+		ID: 1, Title: "Parent", Status: models.StatusDone, IsCompleted: true,
+		Children: []*models.Task{{ID: 2, Title: "Child", Status: models.StatusDone, IsCompleted: true}},
+	}
+	tasks := []*models.Task{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}}
+
+	// get serves url with h as if it had been requested from the given
+	// version of the API.
+	get := func(t *testing.T, h http.HandlerFunc, route, url string, version apiversion.Version) map[string]interface{} {
+		router := mux.NewRouter()
+		router.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+			h(w, r.WithContext(apiversion.NewContext(r.Context(), version)))
+		})
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", url, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return body
+	}
+
+	t.Run("v1 task keeps is_completed", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		mockSvc.On("GetSubtree", mock.Anything, int64(1)).Return(subtree, nil).Once()
+
+		body := get(t, handlers.NewTaskHandler(mockSvc).GetTaskSubtree, "/tasks/{id}/subtree", "/tasks/1/subtree", apiversion.V1)
+		assert.Equal(t, true, body["is_completed"])
+		child := body["children"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, true, child["is_completed"])
+	})
+
+	t.Run("v2 task drops is_completed", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		mockSvc.On("GetSubtree", mock.Anything, int64(1)).Return(subtree, nil).Once()
+
+		body := get(t, handlers.NewTaskHandler(mockSvc).GetTaskSubtree, "/tasks/{id}/subtree", "/tasks/1/subtree", apiversion.V2)
+		assert.NotContains(t, body, "is_completed")
+		assert.Equal(t, "done", body["status"])
+		child := body["children"].([]interface{})[0].(map[string]interface{})
+		assert.NotContains(t, child, "is_completed")
+		assert.Equal(t, "Child", child["title"])
+	})
+
+	t.Run("v1 listing", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		mockSvc.On("GetAllTasks", mock.Anything, mock.Anything, 2, 2).Return(tasks, 5, nil).Once()
+
+		body := get(t, handlers.NewTaskHandler(mockSvc).GetAllTasks, "/tasks", "/tasks?page=2&limit=2", apiversion.V1)
+		assert.Len(t, body["data"], 2)
+		assert.Equal(t, float64(3), body["totalPages"])
+		assert.NotContains(t, body, "meta")
+	})
+
+	t.Run("v2 listing", func(t *testing.T) {
+		mockSvc := new(MockTaskService)
+		mockSvc.On("GetAllTasks", mock.Anything, mock.Anything, 2, 2).Return(tasks, 5, nil).Once()
+
+		body := get(t, handlers.NewTaskHandler(mockSvc).GetAllTasks, "/tasks", "/tasks?page=2&limit=2", apiversion.V2)
+		assert.Len(t, body["data"], 2)
+		assert.Equal(t, map[string]interface{}{
+			"page": float64(2), "limit": float64(2), "total": float64(5), "total_pages": float64(3),
+		}, body["meta"])
+		assert.NotContains(t, body, "totalPages")
+	})
+
+	t.Run("whole listings", func(t *testing.T) {
+		keys := &stubAPIKeys{keys: []*models.APIKey{{ID: 1}, {ID: 2}}}
+		list := func(w http.ResponseWriter, r *http.Request) {
+			ctx := auth.NewContext(r.Context(), &auth.Principal{UserID: 7, Role: models.RoleMember})
+			handlers.NewAPIKeyHandler(keys).ListAPIKeys(w, r.WithContext(ctx))
+		}
+
+		body := get(t, list, "/api-keys", "/api-keys", apiversion.V1)
+		assert.Len(t, body["data"], 2)
+		assert.NotContains(t, body, "meta")
+
+		body = get(t, list, "/api-keys", "/api-keys", apiversion.V2)
+		assert.Len(t, body["data"], 2)
+		assert.Equal(t, map[string]interface{}{"total": float64(2)}, body["meta"])
+	})
+}
+
+// stubAPIKeys lists a fixed set of keys; its other methods are not used.
+type stubAPIKeys struct {
+	services.APIKeyService
+	keys []*models.APIKey
+}
+
+func (s *stubAPIKeys) ListAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	return s.keys, nil
+}
@@ -0,0 +1,92 @@
+package jsonpatch_test
+
+import (
+	"task-manager/internal/jsonpatch"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	// Examples from RFC 7396, appendix A.
+	testCases := map[string]struct {
+		doc, patch, want string
+	}{
+		"replace member":   {`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		"add member":       {`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		"remove member":    {`{"a":"b"}`, `{"a":null}`, `{}`},
+		"nested remove":    {`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		"replace array":    {`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		"non-object patch": {`{"a":"foo"}`, `["c"]`, `["c"]`},
+		"null in new":      {`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		"exact numbers":    {`{"n":1}`, `{"m":12345678901234567890}`, `{"m":12345678901234567890,"n":1}`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := jsonpatch.Merge([]byte(tc.doc), []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+
+	_, err := jsonpatch.Merge([]byte(`{}`), []byte(`{"a":`))
+	assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	// Mostly examples from RFC 6902, appendix A.
+	testCases := map[string]struct {
+		doc, patch, want string
+	}{
+		"add member":           {`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		"add array element":    {`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		"append":               {`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		"remove member":        {`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		"remove element":       {`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		"replace":              {`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		"move":                 {`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		"move element":         {`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		"copy":                 {`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		"test passes":          {`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		"escaped pointer":      {`{"a/b":1,"m~n":2}`, `[{"op":"replace","path":"/a~1b","value":3},{"op":"remove","path":"/m~0n"}]`, `{"a/b":3}`},
+		"add null value":       {`{"foo":"bar"}`, `[{"op":"add","path":"/foo","value":null}]`, `{"foo":null}`},
+		"replace whole":        {`{"foo":"bar"}`, `[{"op":"replace","path":"","value":{"baz":1}}]`, `{"baz":1}`},
+		"object order in test": {`{"o":{"a":1,"b":2}}`, `[{"op":"test","path":"/o","value":{"b":2,"a":1}}]`, `{"o":{"a":1,"b":2}}`},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(tc.doc), []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+
+	invalid := map[string]string{
+		"not a list":         `{"op":"add","path":"/a","value":1}`,
+		"unknown op":         `[{"op":"frob","path":"/a"}]`,
+		"missing value":      `[{"op":"add","path":"/a"}]`,
+		"remove missing":     `[{"op":"remove","path":"/nope"}]`,
+		"replace missing":    `[{"op":"replace","path":"/nope","value":1}]`,
+		"missing parent":     `[{"op":"add","path":"/x/y","value":1}]`,
+		"index out of range": `[{"op":"add","path":"/list/5","value":1}]`,
+		"leading zero":       `[{"op":"remove","path":"/list/01"}]`,
+		"bad pointer":        `[{"op":"remove","path":"a"}]`,
+		"move into child":    `[{"op":"move","from":"/obj","path":"/obj/x"}]`,
+	}
+	for name, patch := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := jsonpatch.Apply([]byte(`{"a":1,"list":[1,2],"obj":{}}`), []byte(patch))
+			assert.ErrorIs(t, err, jsonpatch.ErrInvalidPatch)
+		})
+	}
+
+	t.Run("failed test applies nothing", func(t *testing.T) {
+		doc := []byte(`{"a":1}`)
+		_, err := jsonpatch.Apply(doc, []byte(`[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":"1"}]`))
+		assert.ErrorIs(t, err, jsonpatch.ErrTestFailed)
+		assert.JSONEq(t, `{"a":1}`, string(doc))
+	})
+}
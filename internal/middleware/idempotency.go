@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+	// minIdempotentBody is the least body size NewIdempotency accepts, so
+	// JSON writes are not held to a small attachment limit.
+	minIdempotentBody = 1 << 20
+	// idempotencyLockTimeout is how long a request may hold its key before
+	// the reservation is taken to be abandoned by a process that died.
+	idempotencyLockTimeout = 5 * time.Minute
+)
+
+// IdempotencyStore keeps the responses to writes sent with an
+// Idempotency-Key. Reserve returns the existing record if the key is taken.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	Release(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+}
+
+type Idempotency struct {
+	store   IdempotencyStore
+	ttl     time.Duration
+	maxBody int64
+}
+
+// NewIdempotency keeps responses for ttl. maxBody bounds the request body
+// that is buffered to fingerprint it, and must leave room for the largest
+// attachment upload; it is raised to at least 1 MiB.
+func NewIdempotency(store IdempotencyStore, ttl time.Duration, maxBody int64) *Idempotency {
+	return &Idempotency{store: store, ttl: ttl, maxBody: max(maxBody, minIdempotentBody)}
+}
+
+// Handle makes writes that carry an Idempotency-Key header safe to retry.
+// The first request with a key runs as usual and its response is kept for
+// the configured window; a retry of the same request gets that response
+// again, marked with Idempotent-Replayed. Reusing a key for a different
+// request is rejected with 422, and a retry while the first request is still
+// running with 409. Keys belong to the caller, so Handle must run after
+// Authenticate. Server errors are not kept, so such a request can be retried
+// for real.
+func (i *Idempotency) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || isReadMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !validIdempotencyKey(key) {
+			problem.Write(w, problem.New(http.StatusBadRequest, "invalid_idempotency_key", "Invalid Idempotency-Key header"))
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, i.maxBody+1))
+		if err != nil {
+			problem.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > i.maxBody {
+			problem.Error(w, "Request body too large for an idempotent request", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// The outcome is stored even if the client goes away meanwhile; that
+		// is when it is most likely to retry.
+		ctx := context.WithoutCancel(r.Context())
+		token, err := lockToken()
+		if err != nil {
+			log.Printf("Error creating idempotency lock token: %v", err)
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		now := time.Now()
+		record := &models.IdempotencyRecord{
+			UserID:      principal.UserID,
+			Key:         key,
+			Fingerprint: requestFingerprint(r, body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(i.ttl),
+			LockedUntil: now.Add(idempotencyLockTimeout),
+			LockToken:   token,
+		}
+
+		existing, err := i.store.Reserve(ctx, record)
+		if err != nil {
+			log.Printf("Error reserving idempotency key: %v", err)
+			problem.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				problem.Write(w, problem.New(http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request"))
+			case existing.StatusCode == 0:
+				problem.Write(w, problem.New(http.StatusConflict, "idempotency_key_in_use", "A request with this Idempotency-Key is still in progress"))
+			default:
+				replay(w, existing)
+			}
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w}
+		done := false
+		defer func() {
+			// The handler panicked; let the key be used again.
+			if !done {
+				i.release(ctx, record)
+			}
+		}()
+
+		next.ServeHTTP(rec, r)
+		done = true
+
+		if rec.status == 0 {
+			rec.WriteHeader(http.StatusOK)
+		}
+		if rec.status >= http.StatusInternalServerError {
+			i.release(ctx, record)
+			return
+		}
+
+		record.StatusCode = rec.status
+		record.Header = rec.header
+		record.Body = rec.body.Bytes()
+		held, err := i.store.Complete(ctx, record)
+		if err != nil {
+			log.Printf("Error storing idempotent response: %v", err)
+		} else if !held {
+			log.Printf("Idempotency key %q of user %d was taken over before its response was stored", record.Key, record.UserID)
+		}
+	})
+}
+
+// release lets the key of record be used again, unless another request has
+// taken the reservation over.
+func (i *Idempotency) release(ctx context.Context, record *models.IdempotencyRecord) {
+	held, err := i.store.Release(ctx, record)
+	if err != nil {
+		log.Printf("Error releasing idempotency key: %v", err)
+	} else if !held {
+		log.Printf("Idempotency key %q of user %d was taken over before it was released", record.Key, record.UserID)
+	}
+}
+
+// lockToken returns a random token identifying one holder of a reservation.
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validIdempotencyKey accepts up to 255 printable ASCII characters.
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint identifies what a request asks for, so a key cannot be
+// replayed for a different one.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+"\n"+r.URL.RequestURI()+"\n"+r.Header.Get("Content-Type")+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, record *models.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status != 0 {
+		return
+	}
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,242 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/auth"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryStore keeps idempotency records in a map.
+type memoryStore struct {
+	records map[int64]map[string]*models.IdempotencyRecord
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[int64]map[string]*models.IdempotencyRecord)}
+}
+
+func (s *memoryStore) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if s.records[record.UserID] == nil {
+		s.records[record.UserID] = make(map[string]*models.IdempotencyRecord)
+	}
+	if existing := s.records[record.UserID][record.Key]; existing != nil && existing.ExpiresAt.After(record.CreatedAt) {
+		abandoned := existing.StatusCode == 0 && !existing.LockedUntil.After(record.CreatedAt)
+		if !abandoned {
+			return existing, nil
+		}
+	}
+	stored := *record
+	s.records[record.UserID][record.Key] = &stored
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if !s.holds(record) {
+		return false, nil
+	}
+	stored := *record
+	s.records[record.UserID][record.Key] = &stored
+	return true, nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	if !s.holds(record) {
+		return false, nil
+	}
+	delete(s.records[record.UserID], record.Key)
+	return true, nil
+}
+
+func (s *memoryStore) holds(record *models.IdempotencyRecord) bool {
+	stored := s.records[record.UserID][record.Key]
+	return stored != nil && stored.StatusCode == 0 && stored.LockToken == record.LockToken
+}
+
+// expire makes every reservation look abandoned.
+func (s *memoryStore) expire() {
+	for _, records := range s.records {
+		for _, record := range records {
+			record.LockedUntil = record.CreatedAt
+		}
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	create := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if bytes.Contains(body, []byte("boom")) {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	})
+
+	serve := func(h http.Handler, userID int64, method, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/tasks", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{UserID: userID, Role: models.RoleMember}))
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("retry replays the response", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		first := serve(h, 1, "POST", "k1", `{"title":"a"}`)
+		retry := serve(h, 1, "POST", "k1", `{"title":"a"}`)
+
+		assert.Equal(t, 1, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("key reused for a different request", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		serve(h, 1, "POST", "k1", `{"title":"a"}`)
+		rr := serve(h, 1, "POST", "k1", `{"title":"b"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("keys belong to their user", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		serve(h, 1, "POST", "k1", `{"title":"a"}`)
+		rr := serve(h, 2, "POST", "k1", `{"title":"a"}`)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		store := newMemoryStore()
+		h := middleware.NewIdempotency(store, time.Hour, 0).Handle(create)
+
+		// A first request reserved the key but has not finished.
+		first := serve(middleware.NewIdempotency(store, time.Hour, 0).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rr := serve(h, 1, "POST", "k1", `{}`)
+			assert.Equal(t, http.StatusConflict, rr.Code)
+			w.WriteHeader(http.StatusNoContent)
+		})), 1, "POST", "k1", `{}`)
+
+		assert.Equal(t, http.StatusNoContent, first.Code)
+	})
+
+	t.Run("abandoned reservation is taken over", func(t *testing.T) {
+		calls = 0
+		store := newMemoryStore()
+		h := middleware.NewIdempotency(store, time.Hour, 0).Handle(create)
+
+		// A process reserved the key and died before answering.
+		past := time.Now().Add(-time.Hour)
+		store.records[1] = map[string]*models.IdempotencyRecord{
+			"k1": {UserID: 1, Key: "k1", Fingerprint: "x", CreatedAt: past, ExpiresAt: past.Add(24 * time.Hour), LockedUntil: past.Add(time.Minute)},
+		}
+
+		rr := serve(h, 1, "POST", "k1", `{}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("stale holder loses to a takeover", func(t *testing.T) {
+		for name, status := range map[string]int{"completes": http.StatusCreated, "fails": http.StatusInternalServerError} {
+			t.Run(name, func(t *testing.T) {
+				calls = 0
+				store := newMemoryStore()
+				h := middleware.NewIdempotency(store, time.Hour, 0).Handle(create)
+
+				// The first request outlives its lock, another takes the key
+				// over and finishes, and only then does the first answer.
+				stale := middleware.NewIdempotency(store, time.Hour, 0).Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					store.expire()
+					rr := serve(h, 1, "POST", "k1", `{}`)
+					assert.Equal(t, http.StatusCreated, rr.Code)
+					w.WriteHeader(status)
+					w.Write([]byte(`{"id":2}`))
+				}))
+				serve(stale, 1, "POST", "k1", `{}`)
+
+				retry := serve(h, 1, "POST", "k1", `{}`)
+				assert.Equal(t, 1, calls)
+				assert.Equal(t, `{"id":1}`, retry.Body.String())
+				assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+			})
+		}
+	})
+
+	t.Run("server errors are not kept", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		serve(h, 1, "POST", "k1", `boom`)
+		rr := serve(h, 1, "POST", "k1", `boom`)
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("expired keys run again", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), -time.Second, 0).Handle(create)
+
+		serve(h, 1, "POST", "k1", `{}`)
+		serve(h, 1, "POST", "k1", `{}`)
+
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("reads and requests without a key pass through", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		serve(h, 1, "POST", "", `{}`)
+		serve(h, 1, "POST", "", `{}`)
+		serve(h, 1, "GET", "k1", "")
+		serve(h, 1, "GET", "k1", "")
+
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("body over the limit", func(t *testing.T) {
+		calls = 0
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 2<<20).Handle(create)
+
+		rr := serve(h, 1, "POST", "k1", string(bytes.Repeat([]byte("x"), 2<<20)))
+		assert.Equal(t, http.StatusCreated, rr.Code)
+
+		rr = serve(h, 1, "POST", "k2", string(bytes.Repeat([]byte("x"), 2<<20+1)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		h := middleware.NewIdempotency(newMemoryStore(), time.Hour, 0).Handle(create)
+
+		rr := serve(h, 1, "POST", string(bytes.Repeat([]byte("k"), 256)), `{}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
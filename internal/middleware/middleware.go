@@ -2,60 +2,211 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/problem"
+	"task-manager/internal/services"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = func() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET environment variable not set")
-	}
-	return []byte(secret)
-}()
-
 type contextKey string
 
 const userCtxKey contextKey = "user"
 
-func JWTMiddleware(next http.Handler) http.Handler {
+// RevocationChecker reports whether an access token has been revoked before
+// its expiry.
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// APIKeyAuthenticator resolves a raw API key to the principal owning it.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*auth.Principal, error)
+}
+
+type Authenticator struct {
+	keys        *auth.KeySet
+	revocations RevocationChecker
+	apiKeys     APIKeyAuthenticator
+}
+
+func NewAuthenticator(keys *auth.KeySet, revocations RevocationChecker, apiKeys APIKeyAuthenticator) *Authenticator {
+	return &Authenticator{keys: keys, revocations: revocations, apiKeys: apiKeys}
+}
+
+// Authenticate accepts either "Authorization: Bearer <jwt>" or
+// "Authorization: ApiKey <key>" and puts the caller into the request context.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+			problem.Error(w, "Missing Authorization header", http.StatusUnauthorized)
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+		if len(parts) != 2 {
+			problem.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		ctx := r.Context()
+		switch parts[0] {
+		case "Bearer":
+			claims, principal, ok := a.authenticateJWT(w, r, parts[1])
+			if !ok {
+				return
 			}
-			return jwtSecret, nil
-		})
+			ctx = context.WithValue(ctx, userCtxKey, claims)
+			ctx = auth.NewContext(ctx, principal)
+		case "ApiKey":
+			principal, err := a.apiKeys.AuthenticateAPIKey(ctx, parts[1])
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidToken) {
+					problem.Error(w, "Invalid API key", http.StatusUnauthorized)
+				} else {
+					log.Printf("Error authenticating api key: %v", err)
+					problem.Error(w, "Internal server error", http.StatusInternalServerError)
+				}
+				return
+			}
+			ctx = auth.NewContext(ctx, principal)
+		default:
+			problem.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+			return
+		}
 
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		if principal, _ := auth.FromContext(ctx); principal.ReadOnly && !isReadMethod(r.Method) {
+			problem.Error(w, "API key is read-only", http.StatusForbidden)
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			ctx := context.WithValue(r.Context(), userCtxKey, claims)
-			next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateJWT validates tokenStr and writes the error response itself
+// when it returns false.
+func (a *Authenticator) authenticateJWT(w http.ResponseWriter, r *http.Request, tokenStr string) (jwt.MapClaims, *auth.Principal, bool) {
+	token, err := jwt.Parse(tokenStr, a.keys.Keyfunc, jwt.WithValidMethods(a.keys.Algorithms()))
+
+	if err != nil || !token.Valid {
+		problem.Error(w, "Invalid token", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		problem.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	principal, err := principalFromClaims(claims)
+	if err != nil {
+		problem.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	revoked, err := a.revocations.IsRevoked(r.Context(), principal.TokenID)
+	if err != nil {
+		log.Printf("Error checking token revocation: %v", err)
+		problem.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if revoked {
+		problem.Error(w, "Token revoked", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	return claims, principal, true
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func principalFromClaims(claims jwt.MapClaims) (*auth.Principal, error) {
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject %q: %w", sub, err)
+	}
+
+	// Every access token carries a jti; one without it could never be revoked.
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, fmt.Errorf("missing jti claim")
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, fmt.Errorf("missing exp claim")
+	}
+
+	role, _ := claims["role"].(string)
+	if !models.Role(role).Valid() {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+
+	username, _ := claims["username"].(string)
+	return &auth.Principal{
+		UserID:    userID,
+		Username:  username,
+		Role:      models.Role(role),
+		TokenID:   jti,
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+// RequireRole rejects callers whose role is not in roles with 403. It must run
+// after Authenticate, which puts the caller into the request context.
+func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			for _, role := range roles {
+				if principal.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			problem.Error(w, "Forbidden", http.StatusForbidden)
+		})
+	}
+}
+
+// RequireSession rejects callers who authenticated with an API key with 403.
+// It guards the routes that manage API keys, so a leaked key cannot mint new
+// keys that would outlive its own revocation. It must run after Authenticate.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			problem.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if principal.APIKeyID != 0 {
+			problem.Error(w, "API keys cannot manage API keys", http.StatusForbidden)
 			return
 		}
 
-		http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,39 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/auth"
+	"task-manager/internal/middleware"
+	"task-manager/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireSession(t *testing.T) {
+	h := middleware.RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	testCases := map[string]struct {
+		principal *auth.Principal
+		want      int
+	}{
+		"session":   {principal: &auth.Principal{UserID: 1, Role: models.RoleMember}, want: http.StatusNoContent},
+		"api key":   {principal: &auth.Principal{UserID: 1, Role: models.RoleMember, APIKeyID: 3}, want: http.StatusForbidden},
+		"anonymous": {principal: nil, want: http.StatusUnauthorized},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api-keys", nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.principal))
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tc.want, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-manager/internal/apiversion"
+	"time"
+)
+
+// VersionPolicy describes one mount of the API: the version its handlers
+// answer as and, once it is on its way out, when it was deprecated and when
+// it is due to be switched off.
+type VersionPolicy struct {
+	Version apiversion.Version
+	// Prefix is the path the mount is under, or "" for the unversioned
+	// routes.
+	Prefix string
+	// Deprecated is when the mount was deprecated; zero while it is not.
+	Deprecated time.Time
+	// Sunset is when the mount is due to stop answering; zero if that has
+	// not been decided.
+	Sunset time.Time
+	// Successor is the prefix of the mount clients should move to.
+	Successor string
+}
+
+// APIVersion tells the handlers behind it which version they answer as. On
+// a deprecated mount it also sends Deprecation (RFC 9745), Sunset (RFC 8594)
+// and a successor-version Link to the same path under the successor, and
+// logs each request so the clients still using it can be found before it is
+// switched off.
+func APIVersion(policy VersionPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !policy.Deprecated.IsZero() {
+				h := w.Header()
+				h.Set("Deprecation", "@"+strconv.FormatInt(policy.Deprecated.Unix(), 10))
+				if !policy.Sunset.IsZero() {
+					h.Set("Sunset", policy.Sunset.UTC().Format(http.TimeFormat))
+				}
+				if policy.Successor != "" {
+					successor := policy.Successor + strings.TrimPrefix(r.URL.Path, policy.Prefix)
+					h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+				}
+				log.Printf("Deprecated API %s used: %s %s from %s (%s)",
+					mountName(policy), r.Method, r.URL.Path, r.RemoteAddr, r.UserAgent())
+			}
+
+			next.ServeHTTP(w, r.WithContext(apiversion.NewContext(r.Context(), policy.Version)))
+		})
+	}
+}
+
+func mountName(policy VersionPolicy) string {
+	if policy.Prefix == "" {
+		return "unversioned " + policy.Version.String()
+	}
+	return policy.Prefix
+}
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"task-manager/internal/apiversion"
+	"task-manager/internal/middleware"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIVersion(t *testing.T) {
+	var got apiversion.Version
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = apiversion.FromContext(r.Context())
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		handler := middleware.APIVersion(middleware.VersionPolicy{Version: apiversion.V2, Prefix: "/v2"})(next)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/v2/tasks", nil))
+
+		assert.Equal(t, apiversion.V2, got)
+		assert.Empty(t, rr.Header().Get("Deprecation"))
+		assert.Empty(t, rr.Header().Get("Sunset"))
+		assert.Empty(t, rr.Header().Get("Link"))
+	})
+
+	t.Run("deprecated version", func(t *testing.T) {
+		deprecated := time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC)
+		sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+		handler := middleware.APIVersion(middleware.VersionPolicy{
+			Version:    apiversion.V1,
+			Prefix:     "/v1",
+			Deprecated: deprecated,
+			Sunset:     sunset,
+			Successor:  "/v2",
+		})(next)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/tasks/7", nil))
+
+		assert.Equal(t, apiversion.V1, got)
+		assert.Equal(t, "@1792108800", rr.Header().Get("Deprecation"))
+		assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", rr.Header().Get("Sunset"))
+		assert.Equal(t, `</v2/tasks/7>; rel="successor-version"`, rr.Header().Get("Link"))
+	})
+
+	t.Run("unversioned routes", func(t *testing.T) {
+		handler := middleware.APIVersion(middleware.VersionPolicy{
+			Version:    apiversion.V1,
+			Deprecated: time.Now(),
+			Successor:  "/v1",
+		})(next)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/tasks", nil))
+
+		assert.Equal(t, apiversion.V1, got)
+		assert.Equal(t, `</v1/tasks>; rel="successor-version"`, rr.Header().Get("Link"))
+		assert.Empty(t, rr.Header().Get("Sunset"))
+	})
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+type APIKeyScope string
+
+const (
+	ScopeRead      APIKeyScope = "read"
+	ScopeReadWrite APIKeyScope = "read_write"
+)
+
+func (s APIKeyScope) Valid() bool {
+	return s == ScopeRead || s == ScopeReadWrite
+}
+
+type APIKey struct {
+	ID         int64       `json:"id"`
+	UserID     int64       `json:"user_id"`
+	Name       string      `json:"name"`
+	Prefix     string      `json:"prefix"`
+	KeyHash    string      `json:"-"`
+	Scope      APIKeyScope `json:"scope"`
+	ExpiresAt  *time.Time  `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string      `json:"name" validate:"required"`
+	Scope     APIKeyScope `json:"scope"`
+	ExpiresAt *time.Time  `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse is the only time the plaintext key is returned; only
+// its hash is stored.
+type CreateAPIKeyResponse struct {
+	*APIKey
+	Key string `json:"key"`
+}
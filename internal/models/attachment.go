@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type Attachment struct {
+	ID          int64  `json:"id"`
+	TaskID      int64  `json:"task_id"`
+	UploaderID  int64  `json:"uploader_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// Checksum is the hex-encoded SHA-256 of the contents.
+	Checksum   string    `json:"checksum"`
+	StorageKey string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"task-manager/internal/validation"
+)
+
+// BulkAction is what one operation of a bulk request does to a task.
+type BulkAction string
+
+const (
+	BulkCreate   BulkAction = "create"
+	BulkUpdate   BulkAction = "update"
+	BulkComplete BulkAction = "complete"
+	BulkDelete   BulkAction = "delete"
+	BulkTag      BulkAction = "tag"
+)
+
+func (a BulkAction) Valid() bool {
+	switch a {
+	case BulkCreate, BulkUpdate, BulkComplete, BulkDelete, BulkTag:
+		return true
+	}
+	return false
+}
+
+// BulkOperation is one step of a bulk request. ID names the task for every
+// action but create. Task is a CreateTaskRequest for create and an
+// UpdateTaskRequest for update. A non-zero Version works like If-Match.
+type BulkOperation struct {
+	Action  BulkAction      `json:"action"`
+	ID      int64           `json:"id,omitempty"`
+	Version int64           `json:"version,omitempty"`
+	Task    json.RawMessage `json:"task,omitempty"`
+	TagID   int64           `json:"tag_id,omitempty"`
+}
+
+// BulkRequest runs its operations in order. If Atomic is set they run in a
+// single transaction and the first failure undoes them all; otherwise each
+// one stands alone and a failure does not stop the rest.
+type BulkRequest struct {
+	Atomic     bool             `json:"atomic"`
+	Operations []*BulkOperation `json:"operations"`
+}
+
+type BulkItemStatus string
+
+const (
+	BulkSucceeded BulkItemStatus = "succeeded"
+	BulkFailed    BulkItemStatus = "failed"
+	// BulkRolledBack marks an operation of an atomic batch that succeeded
+	// but was undone because a later one failed.
+	BulkRolledBack BulkItemStatus = "rolled_back"
+	// BulkSkipped marks an operation of an atomic batch that was not run
+	// because an earlier one failed.
+	BulkSkipped BulkItemStatus = "skipped"
+)
+
+// BulkResult reports on the operation at Index of the request. Task is the
+// task as created or updated. A failed operation carries the status, problem
+// code and field errors the matching single-task endpoint would answer with.
+type BulkResult struct {
+	Index     int                     `json:"index"`
+	Action    BulkAction              `json:"action"`
+	ID        int64                   `json:"id,omitempty"`
+	Status    BulkItemStatus          `json:"status"`
+	Code      int                     `json:"code,omitempty"`
+	ErrorCode string                  `json:"error_code,omitempty"`
+	Error     string                  `json:"error,omitempty"`
+	Errors    []validation.FieldError `json:"errors,omitempty"`
+	Task      *Task                   `json:"task,omitempty"`
+	Err       error                   `json:"-"`
+}
+
+type BulkResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []*BulkResult `json:"results"`
+}
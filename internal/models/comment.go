@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+type Comment struct {
+	ID       int64  `json:"id"`
+	TaskID   int64  `json:"task_id"`
+	AuthorID int64  `json:"author_id"`
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+	// Edited is set once the body has been changed after posting.
+	Edited    bool      `json:"edited"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required"`
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord is the outcome of a write sent with an Idempotency-Key,
+// kept until ExpiresAt. StatusCode is zero while the request is still
+// being handled; a reservation not completed by LockedUntil is taken to be
+// abandoned by a process that died, and can be taken over. LockToken tells
+// the request holding a reservation apart from one it was taken from.
+type IdempotencyRecord struct {
+	UserID      int64
+	Key         string
+	Fingerprint string
+	StatusCode  int
+	Header      map[string][]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	LockedUntil time.Time
+	LockToken   string
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// UserIdentity links a local user to an account at an external OpenID
+// Connect provider.
+type UserIdentity struct {
+	ID        int64
+	UserID    int64
+	Issuer    string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCLoginState is what the server remembers between redirecting a user to
+// the provider and handling the callback.
+type OIDCLoginState struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
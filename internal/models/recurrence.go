@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Recurrence is the series a recurring task belongs to. Completing one
+// occurrence creates the next, due on the next date the rule yields after it.
+type Recurrence struct {
+	ID int64 `json:"id"`
+	// Rule is an RFC 5545 RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO".
+	Rule      string     `json:"rule"`
+	StartsAt  time.Time  `json:"starts_at"`
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+	// Occurrence is the task's 1-based position in the series.
+	Occurrence int `json:"occurrence"`
+}
+
+type OccurrencesResponse struct {
+	Rule        string      `json:"rule"`
+	Occurrences []time.Time `json:"occurrences"`
+}
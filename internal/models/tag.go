@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+type Tag struct {
+	ID        int64     `json:"id"`
+	OwnerID   int64     `json:"owner_id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Color string `json:"color"`
+}
+
+type UpdateTagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
@@ -4,24 +4,204 @@ import (
 	"time"
 )
 
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusTodo, StatusInProgress, StatusBlocked, StatusDone, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// statusTransitions lists the statuses each status may move to. Moving to the
+// same status is always allowed.
+var statusTransitions = map[Status][]Status{
+	StatusTodo:       {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+	StatusInProgress: {StatusTodo, StatusBlocked, StatusDone, StatusCancelled},
+	StatusBlocked:    {StatusTodo, StatusInProgress, StatusCancelled},
+	StatusDone:       {StatusTodo, StatusInProgress},
+	StatusCancelled:  {StatusTodo},
+}
+
+func (s Status) CanTransitionTo(next Status) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Progress rolls up the completion of a task's direct children. Cancelled
+// children are not counted.
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
 type Task struct {
-	ID          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     time.Time `json:"due_date"`
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	StartDate   *time.Time `json:"start_date,omitempty"`
+	Priority    Priority   `json:"priority"`
+	Status      Status     `json:"status"`
+	// IsCompleted is derived from Status and kept for existing clients.
 	IsCompleted bool      `json:"is_completed"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     int64     `json:"owner_id"`
+	ParentID    *int64    `json:"parent_id,omitempty"`
+	Progress    *Progress `json:"progress,omitempty"`
+	Tags        []*Tag    `json:"tags"`
+	// BlockedBy lists the IDs of the tasks that must be finished first.
+	BlockedBy    []int64     `json:"blocked_by"`
+	Recurrence   *Recurrence `json:"recurrence,omitempty"`
+	CommentCount int         `json:"comment_count"`
+	// Children is only filled in when a whole subtree is requested.
+	Children  []*Task   `json:"children,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the task is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version counts the writes to the task's own fields. It leads the
+	// task's ETag and is what If-Match is checked against.
+	Version int64 `json:"version"`
 }
 
 type CreateTaskRequest struct {
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description"`
-	DueDate     time.Time `json:"due_date" validate:"required"`
+	Title       string     `json:"title" validate:"required,max=200"`
+	Description string     `json:"description" validate:"max=5000"`
+	DueDate     time.Time  `json:"due_date" validate:"required"`
+	StartDate   *time.Time `json:"start_date"`
+	Priority    Priority   `json:"priority"`
+	Status      Status     `json:"status"`
+	ParentID    *int64     `json:"parent_id"`
+	// Recurrence makes the task the first of a series; see Recurrence.Rule.
+	// It needs a due date, which becomes the start of the series.
+	Recurrence string `json:"recurrence"`
 }
 
+// UpdateTaskRequest is the full new state of a task's editable fields, as
+// sent with PUT. Omitted or null fields are cleared, and priority and status
+// fall back to the same defaults as on creation.
 type UpdateTaskRequest struct {
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	DueDate     time.Time `json:"due_date"`
+	Title       string     `json:"title" validate:"required,max=200"`
+	Description string     `json:"description" validate:"max=5000"`
+	DueDate     *time.Time `json:"due_date"`
+	StartDate   *time.Time `json:"start_date"`
+	Priority    Priority   `json:"priority"`
+	Status      Status     `json:"status"`
+	ParentID    *int64     `json:"parent_id"`
+}
+
+// PatchFormat is the media type of a PATCH request body.
+type PatchFormat string
+
+const (
+	// MergePatch is an RFC 7396 JSON Merge Patch: an object whose members
+	// replace those of the task, with null removing them.
+	MergePatch PatchFormat = "application/merge-patch+json"
+	// JSONPatch is an RFC 6902 JSON Patch: a list of operations on the task.
+	JSONPatch PatchFormat = "application/json-patch+json"
+)
+
+// TaskSortField is a field tasks can be listed by.
+type TaskSortField string
+
+const (
+	SortByCreatedAt TaskSortField = "created_at"
+	SortByUpdatedAt TaskSortField = "updated_at"
+	SortByDueDate   TaskSortField = "due_date"
+	SortByPriority  TaskSortField = "priority"
+	SortByStatus    TaskSortField = "status"
+	SortByTitle     TaskSortField = "title"
+)
+
+func (f TaskSortField) Valid() bool {
+	switch f {
+	case SortByCreatedAt, SortByUpdatedAt, SortByDueDate, SortByPriority, SortByStatus, SortByTitle:
+		return true
+	}
+	return false
+}
+
+// TaskFilter narrows and orders a task listing. Zero fields do not filter;
+// ranges include their start and exclude their end.
+type TaskFilter struct {
+	Completed   *bool
+	Overdue     bool
+	DueFrom     *time.Time
+	DueTo       *time.Time
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	Priorities  []Priority
+	// Tags lists tag names a task must all carry.
+	Tags []string
+	// SortBy defaults to created_at. Priority sorts from low to urgent.
+	SortBy     TaskSortField
+	Descending bool
+}
+
+// TaskSearchResult is a task matching a search, with the matched terms in
+// its title and description wrapped in <mark> tags. Snippet holds the best
+// fragments of the description, and is empty if it had none. The text is
+// HTML-escaped, so both can be put into a page as they are.
+type TaskSearchResult struct {
+	Task           *Task   `json:"task"`
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+// TaskCursor marks a position in a task listing sorted by SortBy: the sort
+// key and ID of the task at the edge of a page. Listing from a cursor
+// returns the tasks after it, or before it when Before is set.
+type TaskCursor struct {
+	SortBy     TaskSortField `json:"s"`
+	Descending bool          `json:"d,omitempty"`
+	Key        interface{}   `json:"k"`
+	ID         int64         `json:"i"`
+	Before     bool          `json:"b,omitempty"`
+}
+
+// TaskPage is one page of a cursor-paginated task listing. Next and Prev are
+// nil at either end of the listing; Total is only counted on request.
+type TaskPage struct {
+	Tasks      []*Task     `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Total      *int        `json:"total,omitempty"`
+	Next       *TaskCursor `json:"-"`
+	Prev       *TaskCursor `json:"-"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type EventAction string
+
+const (
+	EventCreated   EventAction = "created"
+	EventUpdated   EventAction = "updated"
+	EventCompleted EventAction = "completed"
+	EventDeleted   EventAction = "deleted"
+	EventRestored  EventAction = "restored"
+)
+
+func (a EventAction) Valid() bool {
+	switch a {
+	case EventCreated, EventUpdated, EventCompleted, EventDeleted, EventRestored:
+		return true
+	}
+	return false
+}
+
+// FieldChange is the value of one task field before and after a change. From
+// is null for fields set on creation.
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// TaskEvent is one entry of a task's history. ActorID is nil for changes
+// made by the system, such as background jobs.
+type TaskEvent struct {
+	ID        int64                  `json:"id"`
+	TaskID    int64                  `json:"task_id"`
+	ActorID   *int64                 `json:"actor_id"`
+	Actor     string                 `json:"actor"`
+	Action    EventAction            `json:"action"`
+	Changes   map[string]FieldChange `json:"changes"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// EventFilter narrows an audit query. Zero fields do not filter.
+type EventFilter struct {
+	TaskID  int64
+	ActorID int64
+	Action  EventAction
+	From    *time.Time
+	To      *time.Time
+}
@@ -0,0 +1,89 @@
+package oidc_test
+
+import (
+	"context"
+	"task-manager/internal/oidc"
+	"task-manager/internal/oidc/oidctest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	idp, err := oidctest.NewServer("task-manager", "s3cret")
+	require.NoError(t, err)
+	t.Cleanup(idp.Close)
+
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "task-manager",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8080/oidc/callback",
+	})
+	require.NoError(t, err)
+
+	return idp, provider
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp, provider := newProvider(t)
+	idp.SetIdentity(oidctest.Identity{Subject: "abc-123", Email: "ada@example.com", PreferredUsername: "ada"})
+
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code, state, err := idp.Authorize(provider.AuthCodeURL("state-1", "nonce-1", challenge))
+	require.NoError(t, err)
+	assert.Equal(t, "state-1", state)
+
+	rawIDToken, err := provider.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	claims, err := provider.VerifyIDToken(context.Background(), rawIDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "abc-123", claims.Subject)
+	assert.Equal(t, "ada", claims.PreferredUsername)
+	assert.True(t, claims.EmailVerified)
+}
+
+func TestVerifyIDTokenRejectsWrongNonce(t *testing.T) {
+	idp, provider := newProvider(t)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code, _, err := idp.Authorize(provider.AuthCodeURL("state-1", "nonce-1", challenge))
+	require.NoError(t, err)
+	rawIDToken, err := provider.Exchange(context.Background(), code, verifier)
+	require.NoError(t, err)
+
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, "other-nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	idp, provider := newProvider(t)
+
+	_, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code, _, err := idp.Authorize(provider.AuthCodeURL("state-1", "nonce-1", challenge))
+	require.NoError(t, err)
+
+	_, err = provider.Exchange(context.Background(), code, "not-the-verifier")
+	assert.Error(t, err)
+}
+
+func TestNewProviderRejectsIssuerMismatch(t *testing.T) {
+	idp, err := oidctest.NewServer("task-manager", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	_, err = oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:      idp.Issuer() + "/",
+		ClientID:    "task-manager",
+		RedirectURL: "http://localhost:8080/oidc/callback",
+	})
+	assert.Error(t, err)
+}
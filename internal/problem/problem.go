@@ -0,0 +1,66 @@
+// Package problem writes error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"task-manager/internal/validation"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable name for the problem, which Type also ends in; Errors
+// lists invalid fields for validation problems.
+type Problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Code     string                  `json:"code"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+}
+
+// New returns a problem with the given status and code, titled after the
+// status.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write sends p as the response.
+func Write(w http.ResponseWriter, p *Problem) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", ContentType)
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error is the problem+json counterpart of http.Error, for failures that
+// need no more than a status and a message. The code is derived from the
+// status.
+func Error(w http.ResponseWriter, detail string, status int) {
+	Write(w, New(status, StatusCode(status), detail))
+}
+
+// StatusCode is the generic problem code for an HTTP status, such as
+// "not_found" for 404.
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	if status == http.StatusInternalServerError {
+		return "internal_error"
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_")
+}
@@ -0,0 +1,336 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules that
+// recurring tasks support: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+// All dates are evaluated in the time zone of the series start, which for
+// tasks is UTC.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how many periods are searched for occurrences, so a rule
+// whose filters rarely or never match cannot loop for long.
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var untilLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	// Count limits the series to this many occurrences, counting the start.
+	Count int
+	// Until is the last instant an occurrence may fall on.
+	Until *time.Time
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE". A leading
+// "RRULE:" is accepted. Parts outside the supported subset are rejected
+// rather than ignored, so a rule never silently means something else.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidRule)
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: malformed part %q", ErrInvalidRule, part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidRule, key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positiveInt(value)
+		case "COUNT":
+			rule.Count, err = positiveInt(value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseByMonthDay(value)
+		default:
+			err = fmt.Errorf("unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRule)
+	}
+
+	return rule, nil
+}
+
+// String formats the rule in canonical form, so equivalent inputs are
+// stored the same way.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			days = append(days, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayouts[0]))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns up to n occurrences of the series starting at start that fall
+// strictly after after. The start itself is always the first occurrence.
+func (r *Rule) Next(start, after time.Time, n int) []time.Time {
+	var out []time.Time
+	count := 0
+	emit := func(t time.Time) bool {
+		if r.Until != nil && t.After(*r.Until) {
+			return false
+		}
+		count++
+		if r.Count > 0 && count > r.Count {
+			return false
+		}
+		if t.After(after) {
+			out = append(out, t)
+		}
+		return len(out) < n
+	}
+
+	if n <= 0 || !emit(start) {
+		return out
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.candidates(start, period) {
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// candidates lists, in order, the times the rule yields in the given period
+// of the series, where period 0 is the one containing start.
+func (r *Rule) candidates(start time.Time, period int) []time.Time {
+	step := period * r.Interval
+	y, m, d := start.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{at(y, m, d+step)}
+	case Weekly:
+		// Weeks start on Monday, the RFC 5545 default WKST.
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := at(y, m, d-offset+7*step)
+		if len(r.ByDay) == 0 {
+			days = []time.Time{monday.AddDate(0, 0, offset)}
+		} else {
+			for i := 0; i < 7; i++ {
+				days = append(days, monday.AddDate(0, 0, i))
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(step), 1)
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			days = monthDays(first, []int{d})
+		} else {
+			days = monthDays(first, nil)
+		}
+	case Yearly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			// Like monthly, a date that does not exist in a year (29 February)
+			// is skipped rather than moved.
+			days = monthDays(at(y+step, m, 1), []int{d})
+		} else {
+			for month := time.January; month <= time.December; month++ {
+				days = append(days, monthDays(at(y+step, month, 1), nil)...)
+			}
+		}
+	}
+
+	var out []time.Time
+	for _, t := range days {
+		if r.matches(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// matches applies the BYDAY and BYMONTHDAY filters.
+func (r *Rule) matches(t time.Time) bool {
+	if len(r.ByDay) > 0 && !containsWeekday(r.ByDay, t.Weekday()) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		last := daysIn(t)
+		ok := false
+		for _, day := range r.ByMonthDay {
+			if day == t.Day() || (day < 0 && last+day+1 == t.Day()) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// monthDays returns the given days of the month starting at first, or every
+// day of it when days is nil. Days past the end of the month are dropped.
+func monthDays(first time.Time, days []int) []time.Time {
+	last := daysIn(first)
+	var out []time.Time
+	if days == nil {
+		for day := 1; day <= last; day++ {
+			out = append(out, first.AddDate(0, 0, day-1))
+		}
+		return out
+	}
+	for _, day := range days {
+		if day <= last {
+			out = append(out, first.AddDate(0, 0, day-1))
+		}
+	}
+	return out
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func positiveInt(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a positive integer", value)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (*time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A bare date includes the whole day.
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("bad UNTIL %q", value)
+}
+
+func parseByDay(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range strings.Split(value, ",") {
+		day, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported BYDAY value %q", name)
+		}
+		if !containsWeekday(days, day) {
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool {
+		return (days[i]+6)%7 < (days[j]+6)%7
+	})
+	return days, nil
+}
+
+func parseByMonthDay(value string) ([]int, error) {
+	var days []int
+	for _, s := range strings.Split(value, ",") {
+		day, err := strconv.Atoi(s)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, fmt.Errorf("bad BYMONTHDAY value %q", s)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
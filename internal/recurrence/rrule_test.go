@@ -0,0 +1,114 @@
+package recurrence_test
+
+import (
+	"task-manager/internal/recurrence"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, t.Format("2006-01-02 15:04"))
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	rule, err := recurrence.Parse("RRULE:freq=weekly;BYDAY=WE,MO;interval=2;COUNT=4")
+	require.NoError(t, err)
+	assert.Equal(t, recurrence.Weekly, rule.Freq)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Wednesday}, rule.ByDay)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=4", rule.String())
+
+	rule, err = recurrence.Parse("FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20250630")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=-1;UNTIL=20250630T235959Z", rule.String())
+
+	invalid := map[string]string{
+		"empty":           "",
+		"no freq":         "INTERVAL=2",
+		"hourly":          "FREQ=HOURLY",
+		"zero interval":   "FREQ=DAILY;INTERVAL=0",
+		"ordinal byday":   "FREQ=MONTHLY;BYDAY=1MO",
+		"bad monthday":    "FREQ=MONTHLY;BYMONTHDAY=32",
+		"count and until": "FREQ=DAILY;COUNT=2;UNTIL=20250101",
+		"unsupported":     "FREQ=YEARLY;BYMONTH=3",
+		"duplicate":       "FREQ=DAILY;FREQ=WEEKLY",
+		"malformed":       "FREQ",
+	}
+	for name, s := range invalid {
+		t.Run(name, func(t *testing.T) {
+			_, err := recurrence.Parse(s)
+			assert.ErrorIs(t, err, recurrence.ErrInvalidRule)
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := map[string]struct {
+		rule  string
+		start string
+		after string
+		n     int
+		want  []string
+	}{
+		"daily interval": {
+			rule: "FREQ=DAILY;INTERVAL=3", start: "2025-01-30 09:00", after: "2025-01-30 09:00", n: 3,
+			want: []string{"2025-02-02 09:00", "2025-02-05 09:00", "2025-02-08 09:00"},
+		},
+		"weekly byday": {
+			// 2025-01-01 is a Wednesday.
+			rule: "FREQ=WEEKLY;BYDAY=MO,FR", start: "2025-01-01 18:00", after: "2025-01-01 18:00", n: 4,
+			want: []string{"2025-01-03 18:00", "2025-01-06 18:00", "2025-01-10 18:00", "2025-01-13 18:00"},
+		},
+		"fortnightly": {
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", start: "2025-01-01 08:00", after: "2025-01-10 00:00", n: 2,
+			want: []string{"2025-01-14 08:00", "2025-01-28 08:00"},
+		},
+		"monthly skips short months": {
+			rule: "FREQ=MONTHLY", start: "2025-01-31 12:00", after: "2025-01-31 12:00", n: 3,
+			want: []string{"2025-03-31 12:00", "2025-05-31 12:00", "2025-07-31 12:00"},
+		},
+		"last day of month": {
+			rule: "FREQ=MONTHLY;BYMONTHDAY=-1", start: "2025-01-31 12:00", after: "2025-01-31 12:00", n: 3,
+			want: []string{"2025-02-28 12:00", "2025-03-31 12:00", "2025-04-30 12:00"},
+		},
+		"yearly leap day": {
+			rule: "FREQ=YEARLY", start: "2024-02-29 00:00", after: "2024-02-29 00:00", n: 1,
+			want: []string{"2028-02-29 00:00"},
+		},
+		"count includes start": {
+			rule: "FREQ=DAILY;COUNT=3", start: "2025-01-01 00:00", after: "2025-01-01 00:00", n: 10,
+			want: []string{"2025-01-02 00:00", "2025-01-03 00:00"},
+		},
+		"until is inclusive": {
+			rule: "FREQ=WEEKLY;UNTIL=20250115", start: "2025-01-01 10:00", after: "2025-01-01 10:00", n: 10,
+			want: []string{"2025-01-08 10:00", "2025-01-15 10:00"},
+		},
+		"series over": {
+			rule: "FREQ=DAILY;COUNT=2", start: "2025-01-01 00:00", after: "2025-01-02 00:00", n: 1,
+			want: []string{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rule, err := recurrence.Parse(tc.rule)
+			require.NoError(t, err)
+			got := rule.Next(date(tc.start), date(tc.after), tc.n)
+			assert.Equal(t, tc.want, dates(got))
+		})
+	}
+}
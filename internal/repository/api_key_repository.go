@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListByUser(ctx context.Context, userID int64) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, userID, id int64) error
+	TouchLastUsed(ctx context.Context, id int64, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	query := `INSERT INTO api_keys (user_id, name, prefix, key_hash, scope, expires_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scope,
+		key.ExpiresAt,
+		now,
+	).Scan(&key.ID)
+
+	if err != nil {
+		return err
+	}
+
+	key.CreatedAt = now
+
+	return nil
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, key_hash, scope, expires_at, last_used_at, revoked_at, created_at
+				FROM api_keys
+				WHERE key_hash = $1`
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	query := `SELECT id, user_id, name, prefix, key_hash, scope, expires_at, last_used_at, revoked_at, created_at
+				FROM api_keys
+				WHERE user_id = $1
+				ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, userID, id int64) error {
+	query := `UPDATE api_keys
+				SET revoked_at = $1
+				WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+			`
+
+	res, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $1 WHERE id = $2`, at, id)
+	return err
+}
+
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scope,
+		&expiresAt,
+		&lastUsedAt,
+		&revokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ExpiresAt = nullTimePtr(expiresAt)
+	key.LastUsedAt = nullTimePtr(lastUsedAt)
+	key.RevokedAt = nullTimePtr(revokedAt)
+
+	return key, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"time"
+)
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *models.Attachment) error
+	GetByID(ctx context.Context, id int64) (*models.Attachment, error)
+	GetByTask(ctx context.Context, taskID int64) ([]*models.Attachment, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+type attachmentRepository struct {
+	db *sql.DB
+}
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+const attachmentColumns = `id, task_id, uploader_id, filename, content_type, size, checksum, storage_key, created_at`
+
+func NewAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.TaskID,
+		&attachment.UploaderID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.Checksum,
+		&attachment.StorageKey,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// Create stores the metadata of an uploaded attachment by the caller. Callers
+// are expected to have checked that the task is visible to them.
+func (r *attachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.System {
+		return ErrNoCaller
+	}
+
+	query := `INSERT INTO attachments (task_id, uploader_id, filename, content_type, size, checksum, storage_key, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		attachment.TaskID,
+		p.UserID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Checksum,
+		attachment.StorageKey,
+		now,
+	).Scan(&attachment.ID)
+	if err != nil {
+		return err
+	}
+
+	attachment.UploaderID = p.UserID
+	attachment.CreatedAt = now
+
+	return nil
+}
+
+func (r *attachmentRepository) GetByID(ctx context.Context, id int64) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r *attachmentRepository) GetByTask(ctx context.Context, taskID int64) ([]*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + `
+				FROM attachments
+				WHERE task_id = $1
+				ORDER BY created_at ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+func (r *attachmentRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrAttachmentNotFound
+	}
+
+	return nil
+}
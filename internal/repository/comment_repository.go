@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *models.Comment) error
+	GetByID(ctx context.Context, id int64) (*models.Comment, error)
+	GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.Comment, int, error)
+	Update(ctx context.Context, comment *models.Comment) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+// commentColumns selects a comment joined with its author as u.
+const commentColumns = `c.id, c.task_id, c.author_id, u.username, c.body, c.created_at, c.updated_at`
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	comment := &models.Comment{}
+	err := row.Scan(
+		&comment.ID,
+		&comment.TaskID,
+		&comment.AuthorID,
+		&comment.Author,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comment.Edited = comment.UpdatedAt.After(comment.CreatedAt)
+	return comment, nil
+}
+
+// Create stores a comment by the caller. Callers are expected to have
+// checked that the task is visible to them.
+func (r *commentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.System {
+		return ErrNoCaller
+	}
+
+	query := `INSERT INTO comments (task_id, author_id, body, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query, comment.TaskID, p.UserID, comment.Body, now, now).Scan(&comment.ID)
+	if err != nil {
+		return err
+	}
+
+	comment.AuthorID = p.UserID
+	comment.Author = p.Username
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+
+	return nil
+}
+
+func (r *commentRepository) GetByID(ctx context.Context, id int64) (*models.Comment, error) {
+	query := `SELECT ` + commentColumns + `
+				FROM comments c
+				JOIN users u ON u.id = c.author_id
+				WHERE c.id = $1`
+
+	comment, err := scanComment(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// GetByTask returns a page of a task's comments, oldest first, and the total
+// number of comments on the task.
+func (r *commentRepository) GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.Comment, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM comments WHERE task_id = $1`, taskID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + commentColumns + `
+				FROM comments c
+				JOIN users u ON u.id = c.author_id
+				WHERE c.task_id = $1
+				ORDER BY c.created_at ASC, c.id ASC
+				LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, total, rows.Err()
+}
+
+func (r *commentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	query := `UPDATE comments
+				SET body = $1, updated_at = $2
+				WHERE id = $3
+			`
+
+	comment.UpdatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx, query, comment.Body, comment.UpdatedAt, comment.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+
+	comment.Edited = true
+	return nil
+}
+
+func (r *commentRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+
+	return nil
+}
+
+// loadCommentCounts fills in the CommentCount of every task with a single
+// query.
+func loadCommentCounts(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	query := `SELECT task_id, COUNT(*)
+				FROM comments
+				WHERE task_id = ANY($1)
+				GROUP BY task_id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var count int
+		if err := rows.Scan(&taskID, &count); err != nil {
+			return err
+		}
+		byID[taskID].CommentCount = count
+	}
+
+	return rows.Err()
+}
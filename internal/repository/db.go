@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx, so query helpers can run
+// either standalone or inside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// extraColumns lets a scan function read a row that has more columns after
+// the ones it knows about, scanning those into extra.
+type extraColumns struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func (s extraColumns) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// prefixColumns qualifies each column in a comma-separated list with alias,
+// so shared column lists can be used in joins.
+func prefixColumns(alias, columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, col := range parts {
+		parts[i] = alias + "." + strings.TrimSpace(col)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// conn is the handle a repository runs on: the connection pool, or a
+// transaction that several repository calls share.
+type conn interface {
+	dbtx
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (txn, error)
+}
+
+// txn is a transaction, or a savepoint standing in for one.
+type txn interface {
+	dbtx
+	Commit() error
+	Rollback() error
+}
+
+type poolConn struct {
+	*sql.DB
+}
+
+func (c poolConn) BeginTx(ctx context.Context, opts *sql.TxOptions) (txn, error) {
+	tx, err := c.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// txConn runs repository calls inside an open transaction. A call that needs
+// a transaction of its own gets a savepoint instead, so when it fails only
+// its own writes are undone.
+type txConn struct {
+	*sql.Tx
+	savepoints *int
+}
+
+func (c txConn) BeginTx(ctx context.Context, _ *sql.TxOptions) (txn, error) {
+	*c.savepoints++
+	sp := &savepoint{tx: c.Tx, ctx: ctx, name: fmt.Sprintf("sp_%d", *c.savepoints)}
+	if _, err := c.Tx.ExecContext(ctx, `SAVEPOINT `+sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+type savepoint struct {
+	tx   *sql.Tx
+	ctx  context.Context
+	name string
+	done bool
+}
+
+func (s *savepoint) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.tx.ExecContext(ctx, query, args...)
+}
+
+func (s *savepoint) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.tx.QueryContext(ctx, query, args...)
+}
+
+func (s *savepoint) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (s *savepoint) Commit() error {
+	return s.end(`RELEASE SAVEPOINT `)
+}
+
+// Rollback undoes the writes made since the savepoint. Like sql.Tx, it is
+// a no-op returning sql.ErrTxDone once the savepoint has ended, so it can
+// be deferred.
+func (s *savepoint) Rollback() error {
+	return s.end(`ROLLBACK TO SAVEPOINT `)
+}
+
+func (s *savepoint) end(stmt string) error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.tx.ExecContext(s.ctx, stmt+s.name)
+	return err
+}
+
+// TxRunner runs a batch of repository calls as one transaction.
+type TxRunner interface {
+	// WithinTx calls fn with repositories bound to a new transaction, which
+	// is committed if fn returns nil and rolled back otherwise.
+	WithinTx(ctx context.Context, fn func(tasks TaskRepository, tags TagRepository, deps DependencyRepository) error) error
+}
+
+type txRunner struct {
+	db *sql.DB
+}
+
+func NewTxRunner(db *sql.DB) TxRunner {
+	return &txRunner{db: db}
+}
+
+func (r *txRunner) WithinTx(ctx context.Context, fn func(tasks TaskRepository, tags TagRepository, deps DependencyRepository) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	c := txConn{Tx: tx, savepoints: new(int)}
+	if err := fn(&taskRepository{db: c}, &tagRepository{db: c}, &dependencyRepository{db: c}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DependencyRepository interface {
+	Add(ctx context.Context, taskID, blockedByID int64) error
+	Remove(ctx context.Context, taskID, blockedByID int64) error
+	GetBlockers(ctx context.Context, taskID int64) ([]*models.Task, error)
+	GetOpenTasks(ctx context.Context, limit int) ([]*models.Task, error)
+	OpenTaskIDs(ctx context.Context, ids []int64) ([]int64, error)
+}
+
+type dependencyRepository struct {
+	db conn
+}
+
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+func NewDependencyRepository(db *sql.DB) DependencyRepository {
+	return &dependencyRepository{db: poolConn{db}}
+}
+
+// Add records that taskID is blocked by blockedByID. It returns
+// ErrDependencyCycle if blockedByID already depends on taskID, directly or
+// transitively. Callers are expected to have checked that both tasks are
+// visible to them; adding an existing edge is a no-op.
+func (r *dependencyRepository) Add(ctx context.Context, taskID, blockedByID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialise writers so two concurrent inserts cannot each pass the cycle
+	// check and close a loop together.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('task_dependencies'))`); err != nil {
+		return err
+	}
+
+	cycleQuery := `WITH RECURSIVE upstream AS (
+				SELECT blocked_by_id FROM task_dependencies WHERE task_id = $1
+				UNION
+				SELECT d.blocked_by_id FROM task_dependencies d JOIN upstream u ON d.task_id = u.blocked_by_id
+			)
+			SELECT EXISTS (SELECT 1 FROM upstream WHERE blocked_by_id = $2)`
+
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, cycleQuery, blockedByID, taskID).Scan(&cycle); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrDependencyCycle
+	}
+
+	query := `INSERT INTO task_dependencies (task_id, blocked_by_id, created_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT DO NOTHING`
+
+	if _, err := tx.ExecContext(ctx, query, taskID, blockedByID, time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *dependencyRepository) Remove(ctx context.Context, taskID, blockedByID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_dependencies WHERE task_id = $1 AND blocked_by_id = $2`, taskID, blockedByID)
+	return err
+}
+
+func (r *dependencyRepository) GetBlockers(ctx context.Context, taskID int64) ([]*models.Task, error) {
+	query := `SELECT ` + prefixColumns("t", taskColumns) + `
+			FROM task_dependencies d
+			JOIN tasks t ON t.id = d.blocked_by_id
+			WHERE d.task_id = $1
+			AND t.deleted_at IS NULL
+			ORDER BY t.created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetOpenTasks returns up to limit tasks visible to the caller that are
+// neither done nor cancelled, with BlockedBy filled in. The most urgent come
+// first: by priority, then due date with undated tasks last, then ID.
+func (r *dependencyRepository) GetOpenTasks(ctx context.Context, limit int) ([]*models.Task, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + taskColumns + `
+			FROM tasks
+			WHERE status NOT IN ('done', 'cancelled')
+			AND deleted_at IS NULL
+			AND ($1::BIGINT IS NULL OR owner_id = $1)
+			ORDER BY CASE priority
+					WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3
+				END,
+				due_date ASC NULLS LAST,
+				id ASC
+			LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, owner, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// OpenTaskIDs returns those of ids that belong to live tasks that are neither
+// done nor cancelled.
+func (r *dependencyRepository) OpenTaskIDs(ctx context.Context, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := `SELECT id FROM tasks
+			WHERE id = ANY($1)
+			AND status NOT IN ('done', 'cancelled')
+			AND deleted_at IS NULL`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return scanIDs(rows)
+}
+
+// loadDependencies fills in the BlockedBy of every task with a single query.
+func loadDependencies(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		task.BlockedBy = []int64{}
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	query := `SELECT d.task_id, d.blocked_by_id
+				FROM task_dependencies d
+				JOIN tasks t ON t.id = d.blocked_by_id
+				WHERE d.task_id = ANY($1)
+				AND t.deleted_at IS NULL
+				ORDER BY d.blocked_by_id ASC`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, blockedByID int64
+		if err := rows.Scan(&taskID, &blockedByID); err != nil {
+			return err
+		}
+		byID[taskID].BlockedBy = append(byID[taskID].BlockedBy, blockedByID)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	Release(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// maxReserveAttempts bounds how often Reserve starts over when the record
+// it lost to is released before it can be read.
+const maxReserveAttempts = 3
+
+// Reserve stores record as in progress and returns nil, unless a live
+// record for the same user and key exists, in which case it returns that
+// one and stores nothing. An expired record, or a reservation whose lock ran
+// out without a response, is replaced. The stored reservation belongs to
+// record.LockToken, which Complete and Release must present.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	for attempt := 1; ; attempt++ {
+		existing, err := r.reserve(ctx, record)
+		// The record in the way was released between the insert and the
+		// read; the key is free again.
+		if errors.Is(err, sql.ErrNoRows) && attempt < maxReserveAttempts {
+			continue
+		}
+		return existing, err
+	}
+}
+
+func (r *idempotencyRepository) reserve(ctx context.Context, record *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	query := `DELETE FROM idempotency_keys
+				WHERE user_id = $1 AND key = $2
+				AND (expires_at <= $3 OR (status_code IS NULL AND locked_until <= $3))`
+
+	_, err := r.db.ExecContext(ctx, query, record.UserID, record.Key, record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO idempotency_keys (user_id, key, fingerprint, created_at, expires_at, locked_until, lock_token)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (user_id, key) DO NOTHING`
+
+	res, err := r.db.ExecContext(ctx, query, record.UserID, record.Key, record.Fingerprint, record.CreatedAt, record.ExpiresAt, record.LockedUntil, record.LockToken)
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if inserted == 1 {
+		return nil, nil
+	}
+
+	query = `SELECT user_id, key, fingerprint, status_code, headers, body, created_at, expires_at, locked_until
+				FROM idempotency_keys
+				WHERE user_id = $1 AND key = $2`
+
+	existing := &models.IdempotencyRecord{}
+	var status sql.NullInt64
+	var header []byte
+	var lockedUntil sql.NullTime
+	err = r.db.QueryRowContext(ctx, query, record.UserID, record.Key).Scan(
+		&existing.UserID,
+		&existing.Key,
+		&existing.Fingerprint,
+		&status,
+		&header,
+		&existing.Body,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+		&lockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.StatusCode = int(status.Int64)
+	existing.LockedUntil = lockedUntil.Time
+	if header != nil {
+		if err := json.Unmarshal(header, &existing.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response to a reserved request. It reports false, and
+// stores nothing, if the reservation was taken over meanwhile.
+func (r *idempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return false, err
+	}
+
+	query := `UPDATE idempotency_keys
+				SET status_code = $1, headers = $2, body = $3, locked_until = NULL
+				WHERE user_id = $4 AND key = $5
+				AND status_code IS NULL AND lock_token = $6`
+
+	res, err := r.db.ExecContext(ctx, query, record.StatusCode, header, record.Body, record.UserID, record.Key, record.LockToken)
+	if err != nil {
+		return false, err
+	}
+	return affectedOne(res)
+}
+
+// Release drops a reservation whose request produced no response worth
+// keeping, so the key can be used again. It reports false, and drops
+// nothing, if the reservation was taken over meanwhile.
+func (r *idempotencyRepository) Release(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	query := `DELETE FROM idempotency_keys
+				WHERE user_id = $1 AND key = $2
+				AND status_code IS NULL AND lock_token = $3`
+
+	res, err := r.db.ExecContext(ctx, query, record.UserID, record.Key, record.LockToken)
+	if err != nil {
+		return false, err
+	}
+	return affectedOne(res)
+}
+
+func affectedOne(res sql.Result) (bool, error) {
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	return err
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type IdentityRepository interface {
+	GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	SaveLoginState(ctx context.Context, state *models.OIDCLoginState) error
+	ConsumeLoginState(ctx context.Context, state string) (*models.OIDCLoginState, error)
+}
+
+type identityRepository struct {
+	db *sql.DB
+}
+
+var (
+	ErrIdentityExists = errors.New("identity already linked")
+)
+
+func NewIdentityRepository(db *sql.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	query := `SELECT id, user_id, issuer, subject, COALESCE(email, ''), created_at
+				FROM user_identities
+				WHERE issuer = $1 AND subject = $2`
+
+	identity := &models.UserIdentity{}
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Issuer,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (r *identityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, issuer, subject, email, created_at)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		identity.UserID,
+		identity.Issuer,
+		identity.Subject,
+		identity.Email,
+		now,
+	).Scan(&identity.ID)
+
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrIdentityExists
+		}
+		return err
+	}
+
+	identity.CreatedAt = now
+
+	return nil
+}
+
+func (r *identityRepository) SaveLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	query := `INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at)
+				VALUES ($1, $2, $3, $4)`
+
+	// Opportunistically drop abandoned logins so the table stays small.
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < $1`, time.Now()); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, query, state.State, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState deletes and returns the pending login for state, so each
+// state value can complete at most one login.
+func (r *identityRepository) ConsumeLoginState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	query := `DELETE FROM oidc_login_states
+				WHERE state = $1
+				RETURNING state, nonce, code_verifier, expires_at`
+
+	s := &models.OIDCLoginState{}
+	err := r.db.QueryRowContext(ctx, query, state).Scan(&s.State, &s.Nonce, &s.CodeVerifier, &s.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return s, nil
+}
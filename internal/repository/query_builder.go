@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder collects the conditions of a WHERE clause written with ?
+// placeholders and numbers them as PostgreSQL parameters, so filter values
+// are always passed as arguments and never spliced into the SQL.
+type queryBuilder struct {
+	conds []string
+	args  []interface{}
+}
+
+// where adds a condition, binding one value per ? in cond.
+func (b *queryBuilder) where(cond string, values ...interface{}) {
+	if n := strings.Count(cond, "?"); n != len(values) {
+		panic(fmt.Sprintf("queryBuilder: %d placeholders but %d values in %q", n, len(values), cond))
+	}
+
+	var sb strings.Builder
+	i := 0
+	for _, r := range cond {
+		if r == '?' {
+			sb.WriteString(b.arg(values[i]))
+			i++
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	b.conds = append(b.conds, sb.String())
+}
+
+// arg binds value and returns its placeholder, for use outside the WHERE
+// clause such as in LIMIT.
+func (b *queryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// clause returns the WHERE clause, or nothing if there are no conditions.
+func (b *queryBuilder) clause() string {
+	if len(b.conds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(b.conds, "\n\t\t\t\tAND ")
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	GetByID(ctx context.Context, id int64) (*models.Tag, error)
+	GetAll(ctx context.Context) ([]*models.Tag, error)
+	Update(ctx context.Context, tag *models.Tag) error
+	Delete(ctx context.Context, id int64) error
+	Attach(ctx context.Context, taskID, tagID int64) error
+	Detach(ctx context.Context, taskID, tagID int64) error
+}
+
+type tagRepository struct {
+	db dbtx
+}
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+const tagColumns = `id, owner_id, name, color, created_at, updated_at`
+
+func NewTagRepository(db *sql.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func scanTag(row rowScanner) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := row.Scan(
+		&tag.ID,
+		&tag.OwnerID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.System {
+		return ErrNoCaller
+	}
+
+	query := `INSERT INTO tags (owner_id, name, color, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(ctx, query, p.UserID, tag.Name, tag.Color, now, now).Scan(&tag.ID)
+	if err != nil {
+		return tagWriteError(err)
+	}
+
+	tag.OwnerID = p.UserID
+	tag.CreatedAt = now
+	tag.UpdatedAt = now
+
+	return nil
+}
+
+func (r *tagRepository) GetByID(ctx context.Context, id int64) (*models.Tag, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + tagColumns + `
+				FROM tags
+				WHERE id = $1
+				AND ($2::BIGINT IS NULL OR owner_id = $2)`
+
+	tag, err := scanTag(r.db.QueryRowContext(ctx, query, id, owner))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (r *tagRepository) GetAll(ctx context.Context) ([]*models.Tag, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + tagColumns + `
+				FROM tags
+				WHERE ($1::BIGINT IS NULL OR owner_id = $1)
+				ORDER BY name ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*models.Tag{}
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (r *tagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE tags
+				SET name = $1, color = $2, updated_at = $3
+				WHERE id = $4
+				AND ($5::BIGINT IS NULL OR owner_id = $5)
+			`
+
+	tag.UpdatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx, query, tag.Name, tag.Color, tag.UpdatedAt, tag.ID, owner)
+	if err != nil {
+		return tagWriteError(err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+func (r *tagRepository) Delete(ctx context.Context, id int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM tags WHERE id = $1 AND ($2::BIGINT IS NULL OR owner_id = $2)`
+	res, err := r.db.ExecContext(ctx, query, id, owner)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+// Attach links a tag to a task. Callers are expected to have checked that
+// both are visible to them; attaching an already attached tag is a no-op.
+func (r *tagRepository) Attach(ctx context.Context, taskID, tagID int64) error {
+	query := `INSERT INTO task_tags (task_id, tag_id)
+				VALUES ($1, $2)
+				ON CONFLICT DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query, taskID, tagID)
+	return err
+}
+
+func (r *tagRepository) Detach(ctx context.Context, taskID, tagID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = $1 AND tag_id = $2`, taskID, tagID)
+	return err
+}
+
+func tagWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return ErrTagExists
+	}
+	return err
+}
+
+// loadTags fills in the Tags of every task with a single query.
+func loadTags(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		task.Tags = []*models.Tag{}
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	query := `SELECT tt.task_id, ` + prefixColumns("tg", tagColumns) + `
+				FROM task_tags tt
+				JOIN tags tg ON tg.id = tt.tag_id
+				WHERE tt.task_id = ANY($1)
+				ORDER BY tg.name ASC`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		tag := &models.Tag{}
+		err := rows.Scan(
+			&taskID,
+			&tag.ID,
+			&tag.OwnerID,
+			&tag.Name,
+			&tag.Color,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		byID[taskID].Tags = append(byID[taskID].Tags, tag)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"time"
+)
+
+// TaskEventRepository reads the task history. Events are written by the
+// task repository itself, in the same transaction as the change they record.
+type TaskEventRepository interface {
+	GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.TaskEvent, int, error)
+	Search(ctx context.Context, filter *models.EventFilter, limit, offset int) ([]*models.TaskEvent, int, error)
+}
+
+type taskEventRepository struct {
+	db *sql.DB
+}
+
+const taskEventColumns = `id, task_id, actor_id, actor, action, changes, created_at`
+
+// systemActor is recorded as the actor of changes made by internal callers.
+const systemActor = "system"
+
+func NewTaskEventRepository(db *sql.DB) TaskEventRepository {
+	return &taskEventRepository{db: db}
+}
+
+func scanTaskEvent(row rowScanner) (*models.TaskEvent, error) {
+	event := &models.TaskEvent{}
+	var actorID sql.NullInt64
+	var changes []byte
+	err := row.Scan(
+		&event.ID,
+		&event.TaskID,
+		&actorID,
+		&event.Actor,
+		&event.Action,
+		&changes,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if actorID.Valid {
+		event.ActorID = &actorID.Int64
+	}
+	if err := json.Unmarshal(changes, &event.Changes); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// GetByTask returns a page of a task's history, oldest first, and the total
+// number of events. Callers are expected to have checked that the task is
+// visible to them.
+func (r *taskEventRepository) GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.TaskEvent, int, error) {
+	return r.Search(ctx, &models.EventFilter{TaskID: taskID}, limit, offset)
+}
+
+// Search returns a page of the events matching filter across all tasks,
+// oldest first, and the total number of matches. It is not scoped to the
+// caller.
+func (r *taskEventRepository) Search(ctx context.Context, filter *models.EventFilter, limit, offset int) ([]*models.TaskEvent, int, error) {
+	var taskID, actorID, action, from, to interface{}
+	if filter.TaskID != 0 {
+		taskID = filter.TaskID
+	}
+	if filter.ActorID != 0 {
+		actorID = filter.ActorID
+	}
+	if filter.Action != "" {
+		action = string(filter.Action)
+	}
+	if filter.From != nil {
+		from = *filter.From
+	}
+	if filter.To != nil {
+		to = *filter.To
+	}
+
+	where := `WHERE ($1::BIGINT IS NULL OR task_id = $1)
+				AND ($2::BIGINT IS NULL OR actor_id = $2)
+				AND ($3::VARCHAR IS NULL OR action = $3)
+				AND ($4::TIMESTAMPTZ IS NULL OR created_at >= $4)
+				AND ($5::TIMESTAMPTZ IS NULL OR created_at < $5)`
+
+	var total int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM task_events `+where, taskID, actorID, action, from, to).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + taskEventColumns + `
+				FROM task_events
+				` + where + `
+				ORDER BY created_at ASC, id ASC
+				LIMIT $6 OFFSET $7`
+
+	rows, err := r.db.QueryContext(ctx, query, taskID, actorID, action, from, to, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []*models.TaskEvent{}
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// recordTaskEvent appends an event for taskID, attributed to the caller in
+// ctx. It must run in the transaction making the change.
+func recordTaskEvent(ctx context.Context, db dbtx, taskID int64, action models.EventAction, changes map[string]models.FieldChange, at time.Time) error {
+	var actorID interface{}
+	actor := systemActor
+	if p, ok := auth.FromContext(ctx); ok && !p.System {
+		actorID = p.UserID
+		actor = p.Username
+	}
+
+	if changes == nil {
+		changes = map[string]models.FieldChange{}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO task_events (task_id, actor_id, actor, action, changes, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err = db.ExecContext(ctx, query, taskID, actorID, actor, action, data, at)
+	return err
+}
+
+// taskChanges lists the user-editable fields that differ between before and
+// after. A nil before describes a newly created task.
+func taskChanges(before, after *models.Task) map[string]models.FieldChange {
+	if before == nil {
+		before = &models.Task{}
+	}
+
+	changes := make(map[string]models.FieldChange)
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes[field] = models.FieldChange{From: from, To: to}
+		}
+	}
+
+	add("title", optionalString(before.Title), optionalString(after.Title))
+	add("description", optionalString(before.Description), optionalString(after.Description))
+	add("due_date", optionalTime(before.DueDate), optionalTime(after.DueDate))
+	add("start_date", optionalTime(before.StartDate), optionalTime(after.StartDate))
+	add("priority", optionalString(string(before.Priority)), optionalString(string(after.Priority)))
+	add("status", optionalString(string(before.Status)), optionalString(string(after.Status)))
+	add("parent_id", optionalID(before.ParentID), optionalID(after.ParentID))
+
+	return changes
+}
+
+// The optional helpers turn field values into comparable values for
+// taskChanges, with unset fields as nil so they are recorded as null.
+
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func optionalTime(t *time.Time) interface{} {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func optionalID(id *int64) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
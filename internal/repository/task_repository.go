@@ -4,49 +4,252 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"html"
 	"log"
+	"strings"
+	"task-manager/internal/auth"
 	"task-manager/internal/models"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TaskRepository interface {
 	Create(ctx context.Context, task *models.Task) error
 	GetByID(ctx context.Context, id int64) (*models.Task, error)
-	GetAll(ctx context.Context, limit, offset int) ([]*models.Task, int, error)
+	GetAll(ctx context.Context, filter *models.TaskFilter, limit, offset int) ([]*models.Task, int, error)
+	GetPage(ctx context.Context, filter *models.TaskFilter, cursor *models.TaskCursor, limit int) (*models.TaskPage, error)
+	Count(ctx context.Context, filter *models.TaskFilter) (int, error)
 	Update(ctx context.Context, task *models.Task) error
-	MarkComplete(ctx context.Context, id int64) error
-	Delete(ctx context.Context, id int64) error
+	UpdateTree(ctx context.Context, task *models.Task) error
+	MarkComplete(ctx context.Context, id, version int64) error
+	Delete(ctx context.Context, id, version int64) error
 	GetDueTasks(ctx context.Context, from, to time.Time) ([]*models.Task, error)
+	Search(ctx context.Context, terms []string, limit, offset int) ([]*models.TaskSearchResult, int, error)
+	GetChildren(ctx context.Context, parentID int64) ([]*models.Task, error)
+	GetSubtree(ctx context.Context, rootID int64) ([]*models.Task, error)
+	IsAncestor(ctx context.Context, ancestorID, id int64) (bool, error)
+	DeleteTree(ctx context.Context, rootID, version int64) error
+	CompleteTree(ctx context.Context, rootID, version int64) error
+	GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error)
+	Restore(ctx context.Context, id int64) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, []string, error)
+	CreateOccurrence(ctx context.Context, task *models.Task) (bool, error)
+	StopRecurrence(ctx context.Context, recurrenceID int64) error
 }
 
 type taskRepository struct {
-	db *sql.DB
+	db conn
 }
 
 var (
-	ErrTaskNotFound = errors.New("task not found")
+	ErrTaskNotFound  = errors.New("task not found")
+	ErrNoCaller      = errors.New("no authenticated caller in context")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrVersionMismatch is returned by writes expecting a version of the
+	// task other than the current one.
+	ErrVersionMismatch = errors.New("task has been modified")
 )
 
+// taskColumns is the column list scanTask expects, in order.
+const taskColumns = `id, title, description, due_date, start_date, priority, status, is_completed, owner_id, parent_id, recurrence_id, occurrence, created_at, updated_at, deleted_at, version`
+
 func NewTaskRepository(db *sql.DB) TaskRepository {
-	return &taskRepository{db: db}
+	return &taskRepository{db: poolConn{db}}
+}
+
+// ownerScope returns the owner_id every query must be restricted to, taken
+// from the caller in ctx. It returns nil for system callers and admins, which
+// the queries below treat as "all owners".
+func ownerScope(ctx context.Context) (interface{}, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, ErrNoCaller
+	}
+	if p.SeesAllTasks() {
+		return nil, nil
+	}
+	return p.UserID, nil
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	var dueDate, startDate, deletedAt sql.NullTime
+	var ownerID, parentID, recurrenceID, occurrence sql.NullInt64
+	err := row.Scan(
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&dueDate,
+		&startDate,
+		&task.Priority,
+		&task.Status,
+		&task.IsCompleted,
+		&ownerID,
+		&parentID,
+		&recurrenceID,
+		&occurrence,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&deletedAt,
+		&task.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	task.DueDate = nullTimePtr(dueDate)
+	task.StartDate = nullTimePtr(startDate)
+	task.DeletedAt = nullTimePtr(deletedAt)
+	task.OwnerID = ownerID.Int64
+	if parentID.Valid {
+		task.ParentID = &parentID.Int64
+	}
+	if recurrenceID.Valid {
+		task.Recurrence = &models.Recurrence{ID: recurrenceID.Int64, Occurrence: int(occurrence.Int64)}
+	}
+
+	return task, nil
+}
+
+func scanTasks(rows *sql.Rows) ([]*models.Task, error) {
+	var tasks []*models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
 }
 
 func (r *taskRepository) Create(ctx context.Context, task *models.Task) error {
-	query := `INSERT INTO tasks (title, description, due_date, created_at, updated_at)
-				VALUES ($1, $2, $3, $4, $5)
-				RETURNING id
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.System {
+		return ErrNoCaller
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A task created with a rule starts a new series.
+	if task.Recurrence != nil && task.Recurrence.ID == 0 {
+		task.Recurrence.Occurrence = 1
+		if err := insertRecurrence(ctx, tx, task.Recurrence); err != nil {
+			return err
+		}
+	}
+
+	task.OwnerID = p.UserID
+	if err := insertTask(ctx, tx, task, ""); err != nil {
+		return err
+	}
+
+	if err := recordTaskEvent(ctx, tx, task.ID, models.EventCreated, taskChanges(nil, task), task.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateOccurrence stores the next task of a series on behalf of the owner
+// given in task, along with its tags. It reports false, without error, if
+// that occurrence of the series already exists.
+func (r *taskRepository) CreateOccurrence(ctx context.Context, task *models.Task) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = insertTask(ctx, tx, task, "ON CONFLICT (recurrence_id, occurrence) DO NOTHING")
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, tag := range task.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2)`, task.ID, tag.ID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := recordTaskEvent(ctx, tx, task.ID, models.EventCreated, taskChanges(nil, task), task.CreatedAt); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// StopRecurrence ends a series that has a live task visible to the caller.
+// Stopping a series again keeps the time it first stopped.
+func (r *taskRepository) StopRecurrence(ctx context.Context, recurrenceID int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE task_recurrences
+				SET stopped_at = COALESCE(stopped_at, $1)
+				WHERE id = $2
+				AND EXISTS (
+					SELECT 1 FROM tasks
+					WHERE recurrence_id = $2
+					AND deleted_at IS NULL
+					AND ($3::BIGINT IS NULL OR owner_id = $3)
+				)`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), recurrenceID, owner)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func insertTask(ctx context.Context, db dbtx, task *models.Task, onConflict string) error {
+	var recurrenceID, occurrence interface{}
+	if task.Recurrence != nil {
+		recurrenceID = task.Recurrence.ID
+		occurrence = task.Recurrence.Occurrence
+	}
+
+	query := `INSERT INTO tasks (title, description, due_date, start_date, priority, status, owner_id, parent_id,
+					recurrence_id, occurrence, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+				` + onConflict + `
+				RETURNING id, is_completed, version
 			`
 
 	now := time.Now()
-	err := r.db.QueryRowContext(
+	err := db.QueryRowContext(
 		ctx,
 		query,
 		task.Title,
 		task.Description,
 		task.DueDate,
+		task.StartDate,
+		task.Priority,
+		task.Status,
+		task.OwnerID,
+		task.ParentID,
+		recurrenceID,
+		occurrence,
 		now,
 		now,
-	).Scan(&task.ID)
+	).Scan(&task.ID, &task.IsCompleted, &task.Version)
 
 	if err != nil {
 		return err
@@ -58,22 +261,27 @@ func (r *taskRepository) Create(ctx context.Context, task *models.Task) error {
 	return nil
 }
 
+func insertRecurrence(ctx context.Context, db dbtx, rec *models.Recurrence) error {
+	query := `INSERT INTO task_recurrences (rule, starts_at, created_at)
+				VALUES ($1, $2, $3)
+				RETURNING id`
+
+	return db.QueryRowContext(ctx, query, rec.Rule, rec.StartsAt, time.Now()).Scan(&rec.ID)
+}
+
 func (r *taskRepository) GetByID(ctx context.Context, id int64) (*models.Task, error) {
-	query := `SELECT id, title, description, due_date, is_completed, created_at, updated_at
-				FROM tasks
-				WHERE id = $1`
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	task := &models.Task{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.DueDate,
-		&task.IsCompleted,
-		&task.CreatedAt,
-		&task.UpdatedAt,
-	)
+	query := `SELECT ` + taskColumns + `
+				FROM tasks
+				WHERE id = $1
+				AND deleted_at IS NULL
+				AND ($2::BIGINT IS NULL OR owner_id = $2)`
 
+	task, err := scanTask(r.db.QueryRowContext(ctx, query, id, owner))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -81,141 +289,1113 @@ func (r *taskRepository) GetByID(ctx context.Context, id int64) (*models.Task, e
 		return nil, err
 	}
 
+	if err := loadTaskDetails(ctx, r.db, []*models.Task{task}); err != nil {
+		return nil, err
+	}
+
 	return task, nil
 }
-func (r *taskRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+
+// taskSortColumns maps each sort field to the expression it orders by. Only
+// these expressions ever reach ORDER BY.
+var taskSortColumns = map[models.TaskSortField]string{
+	models.SortByCreatedAt: "created_at",
+	models.SortByUpdatedAt: "updated_at",
+	// Tasks without a due date sort after every dated one.
+	models.SortByDueDate:  "COALESCE(due_date, '9999-12-31')",
+	models.SortByPriority: "CASE priority WHEN 'low' THEN 0 WHEN 'medium' THEN 1 WHEN 'high' THEN 2 ELSE 3 END",
+	models.SortByStatus:   "status",
+	models.SortByTitle:    "LOWER(title)",
+}
+
+// GetAll returns a page of the caller's tasks matching filter, which may be
+// nil, and the total number of matches.
+func (r *taskRepository) GetAll(ctx context.Context, filter *models.TaskFilter, limit, offset int) ([]*models.Task, int, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if filter == nil {
+		filter = &models.TaskFilter{}
+	}
+
+	qb := taskFilterQuery(filter, owner)
+
 	var total int
-	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks`).Scan(&total)
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks `+qb.clause(), qb.args...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	query := `SELECT id, title, description, due_date, is_completed, created_at, updated_at
-          FROM tasks
-          ORDER BY created_at DESC
-          LIMIT $1 OFFSET $2`
+	sortColumn, ok := taskSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = taskSortColumns[models.SortByCreatedAt]
+	}
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+
+	query := `SELECT ` + taskColumns + `
+				FROM tasks
+				` + qb.clause() + `
+				ORDER BY ` + sortColumn + ` ` + direction + `, id ` + direction + `
+				LIMIT ` + qb.arg(limit) + ` OFFSET ` + qb.arg(offset)
+
+	rows, err := r.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
 
-	rows, err := r.db.QueryContext(ctx, query, limit, offset)
+	tasks, err := scanTasks(rows)
 	if err != nil {
 		return nil, 0, err
 	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// GetPage returns up to limit of the caller's tasks matching filter, starting
+// from cursor, or from the beginning if cursor is nil. Unlike GetAll it seeks
+// by the sort key rather than skipping rows, so tasks inserted meanwhile do
+// not shift later pages. Callers must check that cursor was issued for the
+// same sort order as filter.
+func (r *taskRepository) GetPage(ctx context.Context, filter *models.TaskFilter, cursor *models.TaskCursor, limit int) (*models.TaskPage, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sortColumn, ok := taskSortColumns[filter.SortBy]
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	qb := taskFilterQuery(filter, owner)
+
+	// Walking backwards runs the query in the opposite order and flips the
+	// result, so the page still comes out in the requested order.
+	backwards := cursor != nil && cursor.Before
+	descending := filter.Descending != backwards
+
+	if cursor != nil {
+		key, err := cursorKey(filter.SortBy, cursor.Key)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if descending {
+			op = "<"
+		}
+		qb.where("("+sortColumn+", id) "+op+" (?, ?)", key, cursor.ID)
+	}
+
+	direction := "ASC"
+	if descending {
+		direction = "DESC"
+	}
+
+	query := `SELECT ` + taskColumns + `, ` + sortColumn + `
+				FROM tasks
+				` + qb.clause() + `
+				ORDER BY ` + sortColumn + ` ` + direction + `, id ` + direction + `
+				LIMIT ` + qb.arg(limit+1)
+
+	rows, err := r.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var tasks []*models.Task
+	var keys []interface{}
 	for rows.Next() {
-		task := &models.Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.DueDate,
-			&task.IsCompleted,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
+		var key interface{}
+		task, err := scanTask(extraColumns{rows, []interface{}{&key}})
 		if err != nil {
-			return nil, 0, err
+			return nil, err
+		}
+		if b, ok := key.([]byte); ok {
+			key = string(b)
 		}
 		tasks = append(tasks, task)
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return tasks, total, nil
+	more := len(tasks) > limit
+	if more {
+		tasks, keys = tasks[:limit], keys[:limit]
+	}
+	if backwards {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+
+	page := &models.TaskPage{Tasks: tasks}
+	if page.Tasks == nil {
+		page.Tasks = []*models.Task{}
+	}
+	if len(tasks) > 0 {
+		edge := func(i int, before bool) *models.TaskCursor {
+			return &models.TaskCursor{SortBy: filter.SortBy, Descending: filter.Descending, Key: keys[i], ID: tasks[i].ID, Before: before}
+		}
+		// There is a page in the direction we came from whenever we came
+		// from a cursor, and one ahead whenever the query found more rows.
+		if more || backwards {
+			page.Next = edge(len(tasks)-1, false)
+		}
+		if (backwards && more) || (!backwards && cursor != nil) {
+			page.Prev = edge(0, true)
+		}
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Count returns how many of the caller's tasks match filter.
+func (r *taskRepository) Count(ctx context.Context, filter *models.TaskFilter) (int, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	qb := taskFilterQuery(filter, owner)
+
+	var total int
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks `+qb.clause(), qb.args...).Scan(&total)
+	return total, err
+}
+
+// cursorKey converts a sort key decoded from a cursor back into the type of
+// the column it was read from.
+func cursorKey(field models.TaskSortField, key interface{}) (interface{}, error) {
+	switch field {
+	case models.SortByCreatedAt, models.SortByUpdatedAt, models.SortByDueDate:
+		s, ok := key.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		return t, nil
+	case models.SortByPriority:
+		n, ok := key.(float64)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		return int64(n), nil
+	case models.SortByStatus, models.SortByTitle:
+		s, ok := key.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		return s, nil
+	}
+	return nil, ErrInvalidCursor
+}
+
+// taskFilterQuery turns filter into the conditions on live tasks visible to
+// owner.
+func taskFilterQuery(filter *models.TaskFilter, owner interface{}) *queryBuilder {
+	qb := &queryBuilder{}
+	qb.where("deleted_at IS NULL")
+	if owner != nil {
+		qb.where("owner_id = ?", owner)
+	}
+
+	if filter.Completed != nil {
+		qb.where("is_completed = ?", *filter.Completed)
+	}
+	if filter.Overdue {
+		qb.where("due_date < ? AND status NOT IN ('done', 'cancelled')", time.Now())
+	}
+
+	ranges := []struct {
+		column   string
+		from, to *time.Time
+	}{
+		{"due_date", filter.DueFrom, filter.DueTo},
+		{"created_at", filter.CreatedFrom, filter.CreatedTo},
+		{"updated_at", filter.UpdatedFrom, filter.UpdatedTo},
+	}
+	for _, rg := range ranges {
+		if rg.from != nil {
+			qb.where(rg.column+" >= ?", *rg.from)
+		}
+		if rg.to != nil {
+			qb.where(rg.column+" < ?", *rg.to)
+		}
+	}
+
+	if len(filter.Priorities) > 0 {
+		priorities := make([]string, len(filter.Priorities))
+		for i, p := range filter.Priorities {
+			priorities[i] = string(p)
+		}
+		qb.where("priority = ANY(?)", pq.Array(priorities))
+	}
+	if len(filter.Tags) > 0 {
+		qb.where(`(SELECT COUNT(DISTINCT tg.name)
+					FROM task_tags tt
+					JOIN tags tg ON tg.id = tt.tag_id
+					WHERE tt.task_id = tasks.id AND tg.name = ANY(?)) = ?`, pq.Array(filter.Tags), len(filter.Tags))
+	}
+
+	return qb
 }
 
 func (r *taskRepository) Update(ctx context.Context, task *models.Task) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateTask(ctx, tx, owner, task); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTree stores task as Update does and, in the same transaction, marks
+// its open descendants as done as CompleteTree does, so completing a task
+// never leaves its subtree half done.
+func (r *taskRepository) UpdateTree(ctx context.Context, task *models.Task) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateTask(ctx, tx, owner, task); err != nil {
+		return err
+	}
+	if err := completeSubtree(ctx, tx, task.ID, owner, task.UpdatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateTask writes the editable fields of task and records the change. It
+// sets task's UpdatedAt, IsCompleted and Version to their new values.
+func updateTask(ctx context.Context, tx txn, owner interface{}, task *models.Task) error {
+	before, err := lockTask(ctx, tx, task.ID, owner, task.Version)
+	if err != nil {
+		return err
+	}
+
 	query := `UPDATE tasks 
-				SET title = $1, description = $2, due_date = $3, updated_at = $4
-				WHERE id = $5
+				SET title = $1, description = $2, due_date = $3, start_date = $4,
+					priority = $5, status = $6, parent_id = $7, updated_at = $8,
+					version = version + 1
+				WHERE id = $9
+				RETURNING is_completed, version
 			`
 
 	task.UpdatedAt = time.Now()
-	_, err := r.db.ExecContext(
+	err = tx.QueryRowContext(
 		ctx,
 		query,
 		task.Title,
 		task.Description,
 		task.DueDate,
+		task.StartDate,
+		task.Priority,
+		task.Status,
+		task.ParentID,
 		task.UpdatedAt,
 		task.ID,
-	)
+	).Scan(&task.IsCompleted, &task.Version)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// Saving a task unchanged is not worth a history entry.
+	if changes := taskChanges(before, task); len(changes) > 0 {
+		return recordTaskEvent(ctx, tx, task.ID, models.EventUpdated, changes, task.UpdatedAt)
+	}
+	return nil
 }
 
-func (r *taskRepository) MarkComplete(ctx context.Context, id int64) error {
-	query := `UPDATE tasks 
-				SET is_completed = true, updated_at = $1
-				WHERE id = $2
-			`
+// MarkComplete sets a task to done. A non-zero version must match the
+// task's current one.
+func (r *taskRepository) MarkComplete(ctx context.Context, id, version int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
 
-	res, err := r.db.ExecContext(ctx, query, time.Now(), id)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	before, err := lockTask(ctx, tx, id, owner, version)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrTaskNotFound
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET status = 'done', updated_at = $1, version = version + 1 WHERE id = $2`, now, id); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Status = models.StatusDone
+	if err := recordTaskEvent(ctx, tx, id, models.EventCompleted, taskChanges(before, &after), now); err != nil {
+		return err
 	}
 
-	return err
+	return tx.Commit()
 }
 
-func (r *taskRepository) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM tasks WHERE id = $1`
-	res, err := r.db.ExecContext(ctx, query, id)
+// Delete moves a task to the trash and turns its live subtasks into
+// top-level tasks. The deleted event lists the subtasks it detached, so
+// Restore can put them back. A non-zero version must match the task's
+// current one.
+func (r *taskRepository) Delete(ctx context.Context, id, version int64) error {
+	owner, err := ownerScope(ctx)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := res.RowsAffected()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if rowsAffected == 0 {
-		return ErrTaskNotFound
+	if _, err := lockTask(ctx, tx, id, owner, version); err != nil {
+		return err
 	}
 
-	return err
-}
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `UPDATE tasks SET deleted_at = $1, version = version + 1 WHERE id = $2`, now, id); err != nil {
+		return err
+	}
 
-func (r *taskRepository) GetDueTasks(ctx context.Context, from, to time.Time) ([]*models.Task, error) {
-	query := `SELECT id, title, description, due_date, is_completed, created_at, updated_at
-			FROM tasks
+	rows, err := tx.QueryContext(ctx, `UPDATE tasks SET parent_id = NULL, updated_at = $1, version = version + 1
+				WHERE parent_id = $2 AND deleted_at IS NULL
+				RETURNING id`, now, id)
+	if err != nil {
+		return err
+	}
+	children, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	var changes map[string]models.FieldChange
+	if len(children) > 0 {
+		changes = map[string]models.FieldChange{detachedSubtasks: {From: children, To: nil}}
+	}
+	if err := recordTaskEvent(ctx, tx, id, models.EventDeleted, changes, now); err != nil {
+		return err
+	}
+	for _, child := range children {
+		detached := map[string]models.FieldChange{"parent_id": {From: id, To: nil}}
+		if err := recordTaskEvent(ctx, tx, child, models.EventUpdated, detached, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// detachedSubtasks is the key under which a deleted event lists the subtasks
+// Delete turned into top-level tasks.
+const detachedSubtasks = "subtasks"
+
+// lockTask reads a live task visible to owner and locks it for the rest of
+// the transaction, so the history records what the change actually replaced.
+// It returns ErrVersionMismatch if version is non-zero and not the task's
+// current version.
+func lockTask(ctx context.Context, tx txn, id int64, owner interface{}, version int64) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + `
+				FROM tasks
+				WHERE id = $1
+				AND deleted_at IS NULL
+				AND ($2::BIGINT IS NULL OR owner_id = $2)
+				FOR UPDATE`
+
+	task, err := scanTask(tx.QueryRowContext(ctx, query, id, owner))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && task.Version != version {
+		return nil, ErrVersionMismatch
+	}
+	return task, nil
+}
+
+// recordTaskEvents records the same event for every task whose ID rows
+// returns, then closes rows. It reports ErrTaskNotFound if there were none.
+func recordTaskEvents(ctx context.Context, tx txn, rows *sql.Rows, action models.EventAction, changes map[string]models.FieldChange, at time.Time) error {
+	ids, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return ErrTaskNotFound
+	}
+
+	for _, id := range ids {
+		if err := recordTaskEvent(ctx, tx, id, action, changes, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanIDs reads the single ID column of every row, then closes rows.
+func scanIDs(rows *sql.Rows) ([]int64, error) {
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	return ids, rows.Err()
+}
+
+func (r *taskRepository) GetDueTasks(ctx context.Context, from, to time.Time) ([]*models.Task, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + taskColumns + `
+			FROM tasks
 			WHERE due_date BETWEEN $1 AND $2 
-			AND is_completed = false
+			AND status NOT IN ('done', 'cancelled')
+			AND deleted_at IS NULL
+			AND ($3::BIGINT IS NULL OR owner_id = $3)
 			ORDER BY due_date ASC
 		`
 	log.Printf("Handler triggered:%v - %v", from, to)
 
-	rows, err := r.db.QueryContext(ctx, query, from, to)
+	rows, err := r.db.QueryContext(ctx, query, from, to, owner)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var tasks []*models.Task
+	return scanTasks(rows)
+}
+
+// ts_headline marks matched terms with these control characters rather than
+// with <mark> tags, so the text can be HTML-escaped before the tags go in.
+// They are removed from the text first so stored ones cannot pose as marks.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+	// searchHighlight are the ts_headline options marking matched terms.
+	searchHighlight = `StartSel=` + highlightStart + `, StopSel=` + highlightStop
+)
+
+// Search returns a page of the caller's tasks whose title or description
+// contain every term, or a word starting with it, best matches first, and
+// the total number of matches.
+func (r *taskRepository) Search(ctx context.Context, terms []string, limit, offset int) ([]*models.TaskSearchResult, int, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tsquery := prefixQuery(terms)
+
+	countQuery := `SELECT COUNT(*)
+				FROM tasks
+				WHERE search_vector @@ to_tsquery('english', $1)
+				AND deleted_at IS NULL
+				AND ($2::BIGINT IS NULL OR owner_id = $2)`
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, countQuery, tsquery, owner).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + prefixColumns("t", taskColumns) + `,
+				ts_rank(t.search_vector, q),
+				ts_headline('english', translate(t.title, $5, ''), q, $6),
+				CASE WHEN COALESCE(t.description, '') <> ''
+					THEN ts_headline('english', translate(t.description, $5, ''), q, $7)
+					ELSE '' END
+			FROM tasks t, to_tsquery('english', $1) q
+			WHERE t.search_vector @@ q
+			AND t.deleted_at IS NULL
+			AND ($2::BIGINT IS NULL OR t.owner_id = $2)
+			ORDER BY ts_rank(t.search_vector, q) DESC, t.id DESC
+			LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, tsquery, owner, limit, offset,
+		highlightStart+highlightStop,
+		searchHighlight+`, HighlightAll=true`,
+		searchHighlight+`, MaxFragments=2, FragmentDelimiter=" … "`)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []*models.TaskSearchResult{}
+	tasks := []*models.Task{}
 	for rows.Next() {
-		task := &models.Task{}
-		err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.DueDate,
-			&task.IsCompleted,
-			&task.CreatedAt,
-			&task.UpdatedAt,
-		)
+		result := &models.TaskSearchResult{}
+		task, err := scanTask(extraColumns{rows, []interface{}{&result.Rank, &result.TitleHighlight, &result.Snippet}})
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
+		result.TitleHighlight = markHighlights(result.TitleHighlight)
+		result.Snippet = markHighlights(result.Snippet)
+		result.Task = task
+		results = append(results, result)
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// markHighlights HTML-escapes a ts_headline result and turns its highlight
+// markers into <mark> tags.
+func markHighlights(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightStart, "<mark>")
+	return strings.ReplaceAll(s, highlightStop, "</mark>")
+}
+
+// prefixQuery builds a tsquery matching documents with a word starting with
+// each of terms. Terms must consist of letters and digits only, so they
+// cannot carry tsquery operators.
+func prefixQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = term + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// GetTrash returns a page of the caller's deleted tasks, most recently
+// deleted first, and the total number of them.
+func (r *taskRepository) GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tasks WHERE deleted_at IS NOT NULL AND ($1::BIGINT IS NULL OR owner_id = $1)`, owner).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + taskColumns + `
+			FROM tasks
+			WHERE deleted_at IS NOT NULL
+			AND ($3::BIGINT IS NULL OR owner_id = $3)
+			ORDER BY deleted_at DESC, id ASC
+			LIMIT $1 OFFSET $2`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, offset, owner)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, 0, err
+	}
+
+	return tasks, total, nil
+}
+
+// Restore takes a task out of the trash together with the descendants that
+// were deleted along with it, and reattaches the subtasks Delete detached
+// from it that are still top-level tasks. A task whose parent is still in the
+// trash comes back as a top-level task.
+func (r *taskRepository) Restore(ctx context.Context, id int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `WITH RECURSIVE root AS (
+				SELECT id, deleted_at FROM tasks
+				WHERE id = $1 AND deleted_at IS NOT NULL AND ($2::BIGINT IS NULL OR owner_id = $2)
+			), subtree AS (
+				SELECT id FROM root
+				UNION
+				SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+				WHERE t.deleted_at = (SELECT deleted_at FROM root)
+			)
+			UPDATE tasks t
+			SET deleted_at = NULL,
+				version = t.version + 1,
+				parent_id = CASE WHEN t.id = $1 AND EXISTS (
+					SELECT 1 FROM tasks p WHERE p.id = t.parent_id AND p.deleted_at IS NOT NULL
+				) THEN NULL ELSE t.parent_id END
+			WHERE t.id IN (SELECT id FROM subtree)
+			RETURNING t.id`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx, query, id, owner)
+	if err != nil {
+		return err
+	}
+	if err := recordTaskEvents(ctx, tx, rows, models.EventRestored, nil, now); err != nil {
+		return err
+	}
+
+	reattach := `UPDATE tasks SET parent_id = $1, updated_at = $2, version = version + 1
+				WHERE id IN (
+					SELECT jsonb_array_elements_text(e.changes->'` + detachedSubtasks + `'->'from')::BIGINT
+					FROM (
+						SELECT changes FROM task_events
+						WHERE task_id = $1 AND action = 'deleted'
+						ORDER BY created_at DESC, id DESC
+						LIMIT 1
+					) e
+				)
+				AND parent_id IS NULL
+				AND deleted_at IS NULL
+				AND owner_id = (SELECT owner_id FROM tasks WHERE id = $1)
+				RETURNING id`
+
+	rows, err = tx.QueryContext(ctx, reattach, id, now)
+	if err != nil {
+		return err
+	}
+	children, err := scanIDs(rows)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		reattached := map[string]models.FieldChange{"parent_id": {From: nil, To: id}}
+		if err := recordTaskEvent(ctx, tx, child, models.EventUpdated, reattached, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Purge permanently removes every task deleted before deletedBefore. It
+// returns how many tasks were removed and the blob keys of their
+// attachments, whose rows go with them and whose contents the caller must
+// now delete.
+func (r *taskRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, []string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the rows first so a task restored meanwhile is left alone.
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM tasks WHERE deleted_at < $1 FOR UPDATE`, deletedBefore)
+	if err != nil {
+		return 0, nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	rows, err = tx.QueryContext(ctx, `DELETE FROM attachments WHERE task_id = ANY($1) RETURNING storage_key`, pq.Array(ids))
+	if err != nil {
+		return 0, nil, err
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return 0, nil, err
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return purged, keys, tx.Commit()
+}
+
+func (r *taskRepository) GetChildren(ctx context.Context, parentID int64) ([]*models.Task, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + taskColumns + `
+			FROM tasks
+			WHERE parent_id = $1
+			AND deleted_at IS NULL
+			AND ($2::BIGINT IS NULL OR owner_id = $2)
+			ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, parentID, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetSubtree returns rootID and all of its descendants as a flat list, or
+// nothing if rootID is not visible to the caller.
+func (r *taskRepository) GetSubtree(ctx context.Context, rootID int64) ([]*models.Task, error) {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `WITH RECURSIVE subtree AS (
+				SELECT id FROM tasks
+				WHERE id = $1 AND deleted_at IS NULL AND ($2::BIGINT IS NULL OR owner_id = $2)
+				UNION
+				SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+				WHERE t.deleted_at IS NULL
+			)
+			SELECT ` + taskColumns + `
+			FROM tasks
+			WHERE id IN (SELECT id FROM subtree)
+			ORDER BY created_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, rootID, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := loadTaskDetails(ctx, r.db, tasks); err != nil {
+		return nil, err
+	}
 
 	return tasks, nil
 }
+
+// IsAncestor reports whether ancestorID appears anywhere above id in the
+// task hierarchy.
+func (r *taskRepository) IsAncestor(ctx context.Context, ancestorID, id int64) (bool, error) {
+	query := `WITH RECURSIVE ancestors AS (
+				SELECT parent_id FROM tasks WHERE id = $2
+				UNION
+				SELECT t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE parent_id = $1)`
+
+	var found bool
+	err := r.db.QueryRowContext(ctx, query, ancestorID, id).Scan(&found)
+	return found, err
+}
+
+// DeleteTree moves rootID and its descendants to the trash with the same
+// timestamp, so restoring the root brings the whole subtree back. A non-zero
+// version must match the root's current one.
+func (r *taskRepository) DeleteTree(ctx context.Context, rootID, version int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := `WITH RECURSIVE subtree AS (
+				SELECT id FROM tasks
+				WHERE id = $1 AND deleted_at IS NULL AND ($2::BIGINT IS NULL OR owner_id = $2)
+				UNION
+				SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+				WHERE t.deleted_at IS NULL
+			)
+			UPDATE tasks SET deleted_at = $3, version = version + 1 WHERE id IN (SELECT id FROM subtree)
+			RETURNING id`
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockTask(ctx, tx, rootID, owner, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx, query, rootID, owner, now)
+	if err != nil {
+		return err
+	}
+	if err := recordTaskEvents(ctx, tx, rows, models.EventDeleted, nil, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CompleteTree marks rootID and every open descendant as done. Tasks already
+// done or cancelled are left alone; whether the others may be completed is
+// for the caller to check. A non-zero version must match the root's current
+// one.
+func (r *taskRepository) CompleteTree(ctx context.Context, rootID, version int64) error {
+	owner, err := ownerScope(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := lockTask(ctx, tx, rootID, owner, version); err != nil {
+		return err
+	}
+	if err := completeSubtree(ctx, tx, rootID, owner, time.Now()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// completeSubtree marks rootID and its descendants that are still open as
+// done, recording a completed event for each.
+func completeSubtree(ctx context.Context, tx txn, rootID int64, owner interface{}, now time.Time) error {
+	query := `WITH RECURSIVE subtree AS (
+				SELECT id FROM tasks
+				WHERE id = $1 AND deleted_at IS NULL AND ($2::BIGINT IS NULL OR owner_id = $2)
+				UNION
+				SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+				WHERE t.deleted_at IS NULL
+			)
+			UPDATE tasks t
+			SET status = 'done', updated_at = $3, version = t.version + 1
+			FROM tasks prev
+			WHERE prev.id = t.id
+			AND t.id IN (SELECT id FROM subtree)
+			AND t.status NOT IN ('done', 'cancelled')
+			RETURNING t.id, prev.status`
+
+	rows, err := tx.QueryContext(ctx, query, rootID, owner, now)
+	if err != nil {
+		return err
+	}
+
+	previous := make(map[int64]models.Status)
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var status models.Status
+		if err := rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return err
+		}
+		previous[id] = status
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		changes := taskChanges(&models.Task{Status: previous[id]}, &models.Task{Status: models.StatusDone})
+		if err := recordTaskEvent(ctx, tx, id, models.EventCompleted, changes, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTaskDetails fills in the fields of tasks that live outside the tasks
+// row, with one query per kind of detail rather than per task.
+func loadTaskDetails(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	if err := loadTags(ctx, db, tasks); err != nil {
+		return err
+	}
+	if err := loadDependencies(ctx, db, tasks); err != nil {
+		return err
+	}
+	if err := loadRecurrences(ctx, db, tasks); err != nil {
+		return err
+	}
+	if err := loadCommentCounts(ctx, db, tasks); err != nil {
+		return err
+	}
+	return loadProgress(ctx, db, tasks)
+}
+
+func loadProgress(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	ids := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		byID[task.ID] = task
+		ids = append(ids, task.ID)
+	}
+
+	query := `SELECT parent_id,
+				COUNT(*) FILTER (WHERE status = 'done'),
+				COUNT(*) FILTER (WHERE status <> 'cancelled')
+			FROM tasks
+			WHERE parent_id = ANY($1)
+			AND deleted_at IS NULL
+			GROUP BY parent_id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var parentID int64
+		progress := &models.Progress{}
+		if err := rows.Scan(&parentID, &progress.Done, &progress.Total); err != nil {
+			return err
+		}
+		if progress.Total > 0 {
+			byID[parentID].Progress = progress
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadRecurrences fills in the series details of every recurring task with a
+// single query.
+func loadRecurrences(ctx context.Context, db dbtx, tasks []*models.Task) error {
+	byRecurrence := make(map[int64][]*models.Task)
+	ids := make([]int64, 0)
+	for _, task := range tasks {
+		if task.Recurrence == nil {
+			continue
+		}
+		if _, ok := byRecurrence[task.Recurrence.ID]; !ok {
+			ids = append(ids, task.Recurrence.ID)
+		}
+		byRecurrence[task.Recurrence.ID] = append(byRecurrence[task.Recurrence.ID], task)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `SELECT id, rule, starts_at, stopped_at
+			FROM task_recurrences
+			WHERE id = ANY($1)`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var rule string
+		var startsAt time.Time
+		var stoppedAt sql.NullTime
+		if err := rows.Scan(&id, &rule, &startsAt, &stoppedAt); err != nil {
+			return err
+		}
+		for _, task := range byRecurrence[id] {
+			task.Recurrence.Rule = rule
+			task.Recurrence.StartsAt = startsAt
+			task.Recurrence.StoppedAt = nullTimePtr(stoppedAt)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID int64, next *models.RefreshToken) error
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID int64) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type tokenRepository struct {
+	db *sql.DB
+}
+
+var (
+	ErrTokenRevoked = errors.New("token already revoked")
+)
+
+func NewTokenRepository(db *sql.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query := `SELECT id, user_id, token_hash, expires_at, revoked_at, created_at
+				FROM refresh_tokens
+				WHERE token_hash = $1`
+
+	token := &models.RefreshToken{}
+	var revokedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&revokedAt,
+		&token.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if revokedAt.Valid {
+		token.RevokedAt = &revokedAt.Time
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes the token with oldID and stores next in a single
+// transaction. It returns ErrTokenRevoked if oldID was already revoked, which
+// happens when the same refresh token is presented twice concurrently.
+func (r *tokenRepository) RotateRefreshToken(ctx context.Context, oldID int64, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE refresh_tokens
+				SET revoked_at = $1
+				WHERE id = $2 AND revoked_at IS NULL`, time.Now(), oldID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenRevoked
+	}
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	query := `UPDATE refresh_tokens
+				SET revoked_at = $1
+				WHERE token_hash = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), tokenHash)
+	return err
+}
+
+func (r *tokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	query := `UPDATE refresh_tokens
+				SET revoked_at = $1
+				WHERE user_id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), userID)
+	return err
+}
+
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, expires_at, revoked_at)
+				VALUES ($1, $2, $3)
+				ON CONFLICT (jti) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, query, jti, expiresAt, time.Now())
+	return err
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// DeleteExpired removes refresh tokens and revocation entries that expired
+// before the given time; an expired access token is rejected on its own, so
+// its revocation entry is no longer needed.
+func (r *tokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, before); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE expires_at < $1`, before)
+	return err
+}
+
+func insertRefreshToken(ctx context.Context, db dbtx, token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at, created_at)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := db.QueryRowContext(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt, now).Scan(&token.ID)
+	if err != nil {
+		return err
+	}
+
+	token.CreatedAt = now
+	return nil
+}
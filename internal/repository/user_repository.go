@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"task-manager/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type UserRepository interface {
+	Create(ctx context.Context, user *models.User) error
+	GetByID(ctx context.Context, id int64) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	UpdatePassword(ctx context.Context, id int64, passwordHash string) error
+	UpdateRole(ctx context.Context, id int64, role models.Role) error
+}
+
+type userRepository struct {
+	db *sql.DB
+}
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username already taken")
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint failure.
+const uniqueViolation = "23505"
+
+func NewUserRepository(db *sql.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	query := `INSERT INTO users (username, password_hash, role, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id
+			`
+
+	now := time.Now()
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		user.Username,
+		user.PasswordHash,
+		user.Role,
+		now,
+		now,
+	).Scan(&user.ID)
+
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return ErrUsernameTaken
+		}
+		return err
+	}
+
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	return nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	query := `SELECT id, username, password_hash, role, created_at, updated_at
+				FROM users
+				WHERE id = $1`
+
+	return r.getOne(ctx, query, id)
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	query := `SELECT id, username, password_hash, role, created_at, updated_at
+				FROM users
+				WHERE username = $1`
+
+	return r.getOne(ctx, query, username)
+}
+
+func (r *userRepository) getOne(ctx context.Context, query string, arg interface{}) (*models.User, error) {
+	user := &models.User{}
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&user.ID,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	query := `UPDATE users
+				SET password_hash = $1, updated_at = $2
+				WHERE id = $3
+			`
+
+	res, err := r.db.ExecContext(ctx, query, passwordHash, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role models.Role) error {
+	query := `UPDATE users
+				SET role = $1, updated_at = $2
+				WHERE id = $3
+			`
+
+	res, err := r.db.ExecContext(ctx, query, role, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
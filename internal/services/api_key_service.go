@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+const (
+	apiKeyPrefix = "tm_"
+	// apiKeyDisplayLength is how much of the key is kept in plaintext so users
+	// can tell their keys apart.
+	apiKeyDisplayLength = 10
+	maxAPIKeyNameLength = 100
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID int64, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error)
+	ListAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id int64) error
+	AuthenticateAPIKey(ctx context.Context, rawKey string) (*auth.Principal, error)
+}
+
+type apiKeyService struct {
+	repo  repository.APIKeyRepository
+	users repository.UserRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, users repository.UserRepository) APIKeyService {
+	return &apiKeyService{repo: repo, users: users}
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID int64, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, ErrInvalidInput
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = models.ScopeReadWrite
+	}
+	if !scope.Valid() {
+		return nil, ErrInvalidInput
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidInput
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	raw := apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:apiKeyDisplayLength],
+		KeyHash:   hashToken(raw),
+		Scope:     scope,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKey: key, Key: raw}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id int64) error {
+	err := s.repo.Revoke(ctx, userID, id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// AuthenticateAPIKey resolves a raw key to the principal of the user who owns
+// it. The principal carries the owner's current role, so demoting a user also
+// narrows what their keys can do.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, rawKey string) (*auth.Principal, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := s.repo.GetByHash(ctx, hashToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if key == nil || key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.users.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidToken
+	}
+
+	// A failure to record usage shouldn't lock the key's owner out.
+	if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+		log.Printf("Error updating api key %d last used: %v", key.ID, err)
+	}
+
+	return &auth.Principal{
+		UserID:   user.ID,
+		Username: user.Username,
+		Role:     user.Role,
+		APIKeyID: key.ID,
+		ReadOnly: key.Scope == models.ScopeRead,
+	}, nil
+}
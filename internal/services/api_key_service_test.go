@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListByUser(ctx context.Context, userID int64) ([]*models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, userID, id int64) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func TestAPIKeyServiceMethods(t *testing.T) {
+	mockRepo := new(MockAPIKeyRepository)
+	mockUsers := new(MockUserRepository)
+	service := services.NewAPIKeyService(mockRepo, mockUsers)
+
+	user := &models.User{ID: 7, Username: "alice", Role: models.RoleMember}
+
+	t.Run("CreateAPIKey", func(t *testing.T) {
+		var stored *models.APIKey
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.APIKey")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.APIKey) }).
+			Return(nil).Once()
+
+		got, err := service.CreateAPIKey(context.Background(), 7, &models.CreateAPIKeyRequest{Name: "ci"})
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(got.Key, "tm_"))
+		assert.Equal(t, models.ScopeReadWrite, stored.Scope)
+		assert.Equal(t, sha256Hex(got.Key), stored.KeyHash)
+		assert.True(t, strings.HasPrefix(got.Key, stored.Prefix))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateAPIKey invalid scope", func(t *testing.T) {
+		got, err := service.CreateAPIKey(context.Background(), 7, &models.CreateAPIKeyRequest{Name: "ci", Scope: "admin"})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+		assert.Nil(t, got)
+	})
+
+	t.Run("AuthenticateAPIKey read only", func(t *testing.T) {
+		key := &models.APIKey{ID: 3, UserID: 7, Scope: models.ScopeRead}
+		mockRepo.On("GetByHash", mock.Anything, sha256Hex("tm_secret")).Return(key, nil).Once()
+		mockUsers.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+		mockRepo.On("TouchLastUsed", mock.Anything, int64(3), mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+		principal, err := service.AuthenticateAPIKey(context.Background(), "tm_secret")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), principal.UserID)
+		assert.Equal(t, models.RoleMember, principal.Role)
+		assert.True(t, principal.ReadOnly)
+
+		mockRepo.AssertExpectations(t)
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("AuthenticateAPIKey rejected", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		testCases := map[string]struct {
+			raw string
+			key *models.APIKey
+		}{
+			"unknown": {raw: "tm_unknown", key: nil},
+			"revoked": {raw: "tm_revoked", key: &models.APIKey{ID: 4, UserID: 7, RevokedAt: &past}},
+			"expired": {raw: "tm_expired", key: &models.APIKey{ID: 5, UserID: 7, ExpiresAt: &past}},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockRepo.On("GetByHash", mock.Anything, sha256Hex(tc.raw)).Return(tc.key, nil).Once()
+
+				principal, err := service.AuthenticateAPIKey(context.Background(), tc.raw)
+				assert.ErrorIs(t, err, services.ErrInvalidToken)
+				assert.Nil(t, principal)
+				mockRepo.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("RevokeAPIKey not found", func(t *testing.T) {
+		mockRepo.On("Revoke", mock.Anything, int64(7), int64(99)).Return(repository.ErrAPIKeyNotFound).Once()
+
+		err := service.RevokeAPIKey(context.Background(), 7, 99)
+		assert.ErrorIs(t, err, services.ErrAPIKeyNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+}
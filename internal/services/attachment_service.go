@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/storage"
+)
+
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment too large")
+	ErrUnsupportedMediaType = errors.New("unsupported attachment type")
+)
+
+const maxFilenameLength = 255
+
+// AttachmentLimits bound what may be uploaded. The content type is sniffed
+// from the file itself rather than taken from the client.
+type AttachmentLimits struct {
+	MaxSize int64
+	// AllowedTypes lists media types such as "application/pdf"; "image/*"
+	// allows every image type.
+	AllowedTypes []string
+}
+
+var DefaultAttachmentLimits = AttachmentLimits{
+	MaxSize: 10 << 20,
+	AllowedTypes: []string{
+		"image/*",
+		"application/pdf",
+		"text/plain",
+		// Office documents sniff as zip archives.
+		"application/zip",
+	},
+}
+
+type AttachmentService interface {
+	Upload(ctx context.Context, taskID int64, filename string, r io.Reader) (*models.Attachment, error)
+	GetAttachments(ctx context.Context, taskID int64) ([]*models.Attachment, error)
+	Download(ctx context.Context, taskID, id int64) (*models.Attachment, io.ReadCloser, error)
+	DeleteAttachment(ctx context.Context, taskID, id int64) error
+}
+
+type attachmentService struct {
+	repo   repository.AttachmentRepository
+	tasks  repository.TaskRepository
+	store  storage.BlobStore
+	limits AttachmentLimits
+}
+
+func NewAttachmentService(repo repository.AttachmentRepository, tasks repository.TaskRepository, store storage.BlobStore, limits AttachmentLimits) AttachmentService {
+	return &attachmentService{repo: repo, tasks: tasks, store: store, limits: limits}
+}
+
+// Upload streams r into the blob store while hashing it, then records the
+// attachment. Nothing is kept if the upload is rejected or fails midway.
+func (s *attachmentService) Upload(ctx context.Context, taskID int64, filename string, r io.Reader) (*models.Attachment, error) {
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	filename = cleanFilename(filename)
+	if filename == "" {
+		return nil, ErrInvalidInput
+	}
+
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil || !s.allowedType(contentType) {
+		return nil, ErrUnsupportedMediaType
+	}
+
+	random, err := randomToken(18)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("tasks/%d/%s", taskID, random)
+
+	hash := sha256.New()
+	body := &sizeLimiter{r: io.TeeReader(br, hash), remaining: s.limits.MaxSize}
+	if err := s.store.Put(ctx, key, body); err != nil {
+		if body.exceeded {
+			return nil, ErrAttachmentTooLarge
+		}
+		return nil, err
+	}
+
+	attachment := &models.Attachment{
+		TaskID:      taskID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        body.read,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	if err := s.repo.Create(ctx, attachment); err != nil {
+		s.deleteBlob(ctx, key)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *attachmentService) GetAttachments(ctx context.Context, taskID int64) ([]*models.Attachment, error) {
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByTask(ctx, taskID)
+}
+
+// Download returns the attachment's metadata and contents. The caller must
+// close the reader.
+func (s *attachmentService) Download(ctx context.Context, taskID, id int64) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.getAttachment(ctx, taskID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc, err := s.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		return nil, nil, err
+	}
+
+	return attachment, rc, nil
+}
+
+func (s *attachmentService) DeleteAttachment(ctx context.Context, taskID, id int64) error {
+	attachment, err := s.getAttachment(ctx, taskID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrAttachmentNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return err
+	}
+
+	s.deleteBlob(ctx, attachment.StorageKey)
+	return nil
+}
+
+func (s *attachmentService) checkTask(ctx context.Context, taskID int64) error {
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+func (s *attachmentService) getAttachment(ctx context.Context, taskID, id int64) (*models.Attachment, error) {
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil || attachment.TaskID != taskID {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// deleteBlob removes a blob whose metadata is already gone. Failing to do so
+// only leaves an unreachable file behind, so it is logged, not returned.
+func (s *attachmentService) deleteBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("Failed to delete attachment blob %s: %v", key, err)
+	}
+}
+
+func (s *attachmentService) allowedType(contentType string) bool {
+	for _, allowed := range s.limits.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanFilename keeps only the last path element of a client-supplied name
+// and drops control characters.
+func cleanFilename(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
+
+// sizeLimiter fails the read that goes past the limit, so a blob store
+// aborts the upload instead of storing an oversized file.
+type sizeLimiter struct {
+	r         io.Reader
+	remaining int64
+	read      int64
+	exceeded  bool
+}
+
+func (l *sizeLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		l.exceeded = true
+		return n, ErrAttachmentTooLarge
+	}
+	return n, err
+}
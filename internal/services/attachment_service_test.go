@@ -0,0 +1,157 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"task-manager/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAttachmentRepository struct {
+	mock.Mock
+}
+
+func (m *MockAttachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	args := m.Called(ctx, attachment)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepository) GetByID(ctx context.Context, id int64) (*models.Attachment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) GetByTask(ctx context.Context, taskID int64) ([]*models.Attachment, error) {
+	args := m.Called(ctx, taskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Attachment), args.Error(1)
+}
+
+func (m *MockAttachmentRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// countBlobs returns how many files the local store holds under dir.
+func countBlobs(t *testing.T, dir string) int {
+	n := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			n++
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return n
+}
+
+func TestAttachmentServiceMethods(t *testing.T) {
+	ctx := context.Background()
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 100)...)
+	sum := sha256.Sum256(png)
+
+	setup := func(t *testing.T) (services.AttachmentService, *MockAttachmentRepository, string) {
+		dir := t.TempDir()
+		store, err := storage.NewLocalStore(dir)
+		require.NoError(t, err)
+
+		mockRepo := new(MockAttachmentRepository)
+		mockTasks := new(MockTaskRepository)
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1}, nil)
+		mockTasks.On("GetByID", mock.Anything, int64(2)).Return(nil, nil)
+
+		limits := services.AttachmentLimits{MaxSize: 1024, AllowedTypes: []string{"image/*", "application/pdf"}}
+		return services.NewAttachmentService(mockRepo, mockTasks, store, limits), mockRepo, dir
+	}
+
+	t.Run("Upload and download", func(t *testing.T) {
+		service, mockRepo, dir := setup(t)
+
+		var stored *models.Attachment
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Attachment")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.Attachment) }).
+			Return(nil).Once()
+
+		attachment, err := service.Upload(ctx, 1, `C:\Users\me\shot.png`, bytes.NewReader(png))
+		require.NoError(t, err)
+		assert.Equal(t, "shot.png", attachment.Filename)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, int64(len(png)), attachment.Size)
+		assert.Equal(t, hex.EncodeToString(sum[:]), attachment.Checksum)
+		assert.Equal(t, 1, countBlobs(t, dir))
+
+		mockRepo.On("GetByID", mock.Anything, int64(9)).Return(stored, nil).Once()
+		_, rc, err := service.Download(ctx, 1, 9)
+		require.NoError(t, err)
+		defer rc.Close()
+		got, _ := io.ReadAll(rc)
+		assert.Equal(t, png, got)
+	})
+
+	t.Run("Upload too large", func(t *testing.T) {
+		service, mockRepo, dir := setup(t)
+
+		big := append(append([]byte{}, png...), bytes.Repeat([]byte{1}, 2048)...)
+		_, err := service.Upload(ctx, 1, "big.png", bytes.NewReader(big))
+		assert.ErrorIs(t, err, services.ErrAttachmentTooLarge)
+		assert.Equal(t, 0, countBlobs(t, dir))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Upload disallowed type", func(t *testing.T) {
+		service, _, dir := setup(t)
+
+		_, err := service.Upload(ctx, 1, "run.sh", bytes.NewReader([]byte("#!/bin/sh\nrm -rf /\n")))
+		assert.ErrorIs(t, err, services.ErrUnsupportedMediaType)
+		assert.Equal(t, 0, countBlobs(t, dir))
+	})
+
+	t.Run("Upload to hidden task", func(t *testing.T) {
+		service, _, _ := setup(t)
+
+		_, err := service.Upload(ctx, 2, "shot.png", bytes.NewReader(png))
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	})
+
+	t.Run("Delete removes blob", func(t *testing.T) {
+		service, mockRepo, dir := setup(t)
+
+		var stored *models.Attachment
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Attachment")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.Attachment) }).
+			Return(nil).Once()
+		_, err := service.Upload(ctx, 1, "shot.png", bytes.NewReader(png))
+		require.NoError(t, err)
+
+		mockRepo.On("GetByID", mock.Anything, int64(9)).Return(stored, nil).Once()
+		mockRepo.On("Delete", mock.Anything, int64(9)).Return(nil).Once()
+
+		require.NoError(t, service.DeleteAttachment(ctx, 1, 9))
+		assert.Equal(t, 0, countBlobs(t, dir))
+	})
+
+	t.Run("Attachment of another task", func(t *testing.T) {
+		service, mockRepo, _ := setup(t)
+
+		mockRepo.On("GetByID", mock.Anything, int64(9)).Return(&models.Attachment{ID: 9, TaskID: 3}, nil).Once()
+
+		_, _, err := service.Download(ctx, 1, 9)
+		assert.ErrorIs(t, err, services.ErrAttachmentNotFound)
+	})
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+)
+
+type AuditService interface {
+	GetTaskHistory(ctx context.Context, taskID int64, limit, offset int) ([]*models.TaskEvent, int, error)
+	SearchEvents(ctx context.Context, filter *models.EventFilter, limit, offset int) ([]*models.TaskEvent, int, error)
+}
+
+type auditService struct {
+	repo  repository.TaskEventRepository
+	tasks repository.TaskRepository
+}
+
+func NewAuditService(repo repository.TaskEventRepository, tasks repository.TaskRepository) AuditService {
+	return &auditService{repo: repo, tasks: tasks}
+}
+
+// GetTaskHistory returns the history of a task visible to the caller.
+func (s *auditService) GetTaskHistory(ctx context.Context, taskID int64, limit, offset int) ([]*models.TaskEvent, int, error) {
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if task == nil {
+		return nil, 0, ErrTaskNotFound
+	}
+	return s.repo.GetByTask(ctx, taskID, limit, offset)
+}
+
+// SearchEvents queries the history of every task, including deleted ones.
+// It is meant for admins; the route restricts who may call it.
+func (s *auditService) SearchEvents(ctx context.Context, filter *models.EventFilter, limit, offset int) ([]*models.TaskEvent, int, error) {
+	if filter.Action != "" && !filter.Action.Valid() {
+		return nil, 0, ErrInvalidInput
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, 0, ErrInvalidInput
+	}
+	return s.repo.Search(ctx, filter, limit, offset)
+}
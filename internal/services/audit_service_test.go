@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTaskEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockTaskEventRepository) GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.TaskEvent, int, error) {
+	args := m.Called(ctx, taskID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.TaskEvent), args.Int(1), args.Error(2)
+}
+
+func (m *MockTaskEventRepository) Search(ctx context.Context, filter *models.EventFilter, limit, offset int) ([]*models.TaskEvent, int, error) {
+	args := m.Called(ctx, filter, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.TaskEvent), args.Int(1), args.Error(2)
+}
+
+func TestAuditServiceMethods(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockTaskEventRepository)
+	mockTasks := new(MockTaskRepository)
+	service := services.NewAuditService(mockRepo, mockTasks)
+
+	actor := int64(7)
+	history := []*models.TaskEvent{
+		{ID: 1, TaskID: 1, ActorID: &actor, Actor: "alice", Action: models.EventCreated},
+		{ID: 2, TaskID: 1, ActorID: &actor, Actor: "alice", Action: models.EventUpdated, Changes: map[string]models.FieldChange{
+			"due_date": {From: "2025-01-06T09:00:00Z", To: "2025-01-08T09:00:00Z"},
+		}},
+	}
+
+	t.Run("GetTaskHistory", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1}, nil).Once()
+		mockRepo.On("GetByTask", mock.Anything, int64(1), 10, 0).Return(history, 2, nil).Once()
+
+		events, total, err := service.GetTaskHistory(ctx, 1, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Equal(t, history, events)
+	})
+
+	t.Run("GetTaskHistory not visible", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(2)).Return(nil, nil).Once()
+
+		_, _, err := service.GetTaskHistory(ctx, 2, 10, 0)
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+		mockRepo.AssertNotCalled(t, "GetByTask", mock.Anything, int64(2), mock.Anything, mock.Anything)
+	})
+
+	t.Run("SearchEvents", func(t *testing.T) {
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		filter := &models.EventFilter{ActorID: 7, Action: models.EventUpdated, From: &from}
+		mockRepo.On("Search", mock.Anything, filter, 20, 20).Return(history[1:], 21, nil).Once()
+
+		events, total, err := service.SearchEvents(ctx, filter, 20, 20)
+		assert.NoError(t, err)
+		assert.Equal(t, 21, total)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("SearchEvents invalid filter", func(t *testing.T) {
+		from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		to := from.Add(-time.Hour)
+		filters := map[string]*models.EventFilter{
+			"unknown action": {Action: "renamed"},
+			"empty range":    {From: &from, To: &to},
+		}
+		for name, filter := range filters {
+			t.Run(name, func(t *testing.T) {
+				_, _, err := service.SearchEvents(ctx, filter, 10, 0)
+				assert.ErrorIs(t, err, services.ErrInvalidInput)
+			})
+		}
+	})
+}
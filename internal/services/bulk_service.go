@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+)
+
+var ErrBatchTooLarge = errors.New("too many operations in batch")
+
+const DefaultMaxBatchSize = 100
+
+// errBatchFailed aborts the transaction of an atomic batch; the failure
+// itself is reported on the operation's result.
+var errBatchFailed = errors.New("batch operation failed")
+
+// ServiceFactory builds the task and tag services for a set of repositories,
+// so an atomic batch can run the same rules against repositories bound to
+// its transaction.
+type ServiceFactory func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) (TaskService, TagService)
+
+type BulkService interface {
+	Execute(ctx context.Context, req *models.BulkRequest) (*models.BulkResponse, error)
+}
+
+type bulkService struct {
+	runner       repository.TxRunner
+	tasks        TaskService
+	tags         TagService
+	services     ServiceFactory
+	maxBatchSize int
+}
+
+func NewBulkService(runner repository.TxRunner, tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository, services ServiceFactory, maxBatchSize int) BulkService {
+	s := &bulkService{runner: runner, services: services, maxBatchSize: maxBatchSize}
+	s.tasks, s.tags = services(tasks, tags, deps)
+	return s
+}
+
+// Execute runs the operations of req in order and reports on each. Only a
+// request that cannot run at all is an error; failed operations are
+// reported in the response.
+func (s *bulkService) Execute(ctx context.Context, req *models.BulkRequest) (*models.BulkResponse, error) {
+	if len(req.Operations) == 0 {
+		return nil, ErrInvalidInput
+	}
+	if len(req.Operations) > s.maxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d allowed", ErrBatchTooLarge, s.maxBatchSize)
+	}
+
+	resp := &models.BulkResponse{Atomic: req.Atomic, Results: make([]*models.BulkResult, len(req.Operations))}
+	for i, op := range req.Operations {
+		resp.Results[i] = &models.BulkResult{Index: i, Action: op.Action, ID: op.ID, Status: models.BulkSkipped}
+	}
+
+	if !req.Atomic {
+		for i, op := range req.Operations {
+			s.run(ctx, s.tasks, s.tags, op, resp.Results[i])
+		}
+	} else {
+		err := s.runner.WithinTx(ctx, func(taskRepo repository.TaskRepository, tagRepo repository.TagRepository, depRepo repository.DependencyRepository) error {
+			tasks, tags := s.services(taskRepo, tagRepo, depRepo)
+			for i, op := range req.Operations {
+				if !s.run(ctx, tasks, tags, op, resp.Results[i]) {
+					return errBatchFailed
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			for _, result := range resp.Results {
+				if result.Status == models.BulkSucceeded {
+					result.Status = models.BulkRolledBack
+					result.Task = nil
+					if result.Action == models.BulkCreate {
+						// The row it reported no longer exists.
+						result.ID = 0
+					}
+				}
+			}
+			if !errors.Is(err, errBatchFailed) {
+				return nil, err
+			}
+		}
+	}
+
+	for _, result := range resp.Results {
+		if result.Status == models.BulkSucceeded {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	return resp, nil
+}
+
+// run carries out one operation and records how it went on result,
+// reporting whether it succeeded.
+func (s *bulkService) run(ctx context.Context, tasks TaskService, tags TagService, op *models.BulkOperation, result *models.BulkResult) bool {
+	task, err := s.apply(ctx, tasks, tags, op)
+	if err != nil {
+		result.Status = models.BulkFailed
+		result.Err = err
+		return false
+	}
+
+	result.Status = models.BulkSucceeded
+	if task != nil {
+		result.ID = task.ID
+		result.Task = task
+	}
+	return true
+}
+
+func (s *bulkService) apply(ctx context.Context, tasks TaskService, tags TagService, op *models.BulkOperation) (*models.Task, error) {
+	if !op.Action.Valid() {
+		return nil, fmt.Errorf("%w: unknown action %q", ErrInvalidInput, op.Action)
+	}
+	if op.Action != models.BulkCreate && op.ID <= 0 {
+		return nil, fmt.Errorf("%w: %s needs a task id", ErrInvalidInput, op.Action)
+	}
+
+	switch op.Action {
+	case models.BulkCreate:
+		req := &models.CreateTaskRequest{}
+		if err := decodeBulkTask(op.Task, req); err != nil {
+			return nil, err
+		}
+		return tasks.CreateTask(ctx, req)
+	case models.BulkUpdate:
+		req := &models.UpdateTaskRequest{}
+		if err := decodeBulkTask(op.Task, req); err != nil {
+			return nil, err
+		}
+		return tasks.UpdateTask(ctx, op.ID, req, op.Version)
+	case models.BulkComplete:
+		return nil, tasks.MarkTaskComplete(ctx, op.ID, op.Version)
+	case models.BulkDelete:
+		return nil, tasks.DeleteTask(ctx, op.ID, op.Version)
+	default:
+		if op.TagID <= 0 {
+			return nil, fmt.Errorf("%w: tag needs a tag_id", ErrInvalidInput)
+		}
+		return nil, tags.AttachTag(ctx, op.ID, op.TagID)
+	}
+}
+
+func decodeBulkTask(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: task is required", ErrInvalidInput)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
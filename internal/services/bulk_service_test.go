@@ -0,0 +1,202 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockTxRunner runs the batch against its own repositories, standing in
+// for ones bound to a transaction, and records whether it would commit.
+type MockTxRunner struct {
+	tasks     *MockTaskRepository
+	tags      *MockTagRepository
+	deps      *MockDependencyRepository
+	committed bool
+}
+
+func (m *MockTxRunner) WithinTx(ctx context.Context, fn func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) error) error {
+	err := fn(m.tasks, m.tags, m.deps)
+	m.committed = err == nil
+	return err
+}
+
+func newBulkService(runner *MockTxRunner, tasks *MockTaskRepository, tags *MockTagRepository, maxBatchSize int) services.BulkService {
+	return services.NewBulkService(runner, tasks, tags, new(MockDependencyRepository),
+		func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) (services.TaskService, services.TagService) {
+			return services.NewTaskService(tasks), services.NewTagService(tags, tasks)
+		},
+		maxBatchSize,
+	)
+}
+
+func bulkStatuses(resp *models.BulkResponse) []models.BulkItemStatus {
+	out := make([]models.BulkItemStatus, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		out = append(out, result.Status)
+	}
+	return out
+}
+
+func TestBulkService(t *testing.T) {
+	ctx := context.Background()
+	open := func(id int64) *models.Task {
+		return &models.Task{ID: id, Title: "T", OwnerID: 7, Status: models.StatusTodo, Version: 2}
+	}
+	ops := func() []*models.BulkOperation {
+		return []*models.BulkOperation{
+			{Action: models.BulkComplete, ID: 1},
+			{Action: models.BulkDelete, ID: 2, Version: 5},
+			{Action: models.BulkCreate, Task: json.RawMessage(`{"title":"New","priority":"high","due_date":"2030-01-01T00:00:00Z"}`)},
+			{Action: models.BulkTag, ID: 1, TagID: 9},
+		}
+	}
+
+	t.Run("best effort reports each operation", func(t *testing.T) {
+		mockTasks := new(MockTaskRepository)
+		mockTags := new(MockTagRepository)
+		runner := &MockTxRunner{}
+		service := newBulkService(runner, mockTasks, mockTags, 10)
+
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(open(1), nil)
+		mockTasks.On("MarkComplete", mock.Anything, int64(1), int64(2)).Return(nil).Once()
+		mockTasks.On("Delete", mock.Anything, int64(2), int64(5)).Return(repository.ErrVersionMismatch).Once()
+		mockTasks.On("Create", mock.Anything, mock.MatchedBy(func(task *models.Task) bool {
+			return task.Title == "New" && task.Priority == models.PriorityHigh
+		})).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.Task).ID = 3
+		}).Return(nil).Once()
+		mockTags.On("GetByID", mock.Anything, int64(9)).Return(&models.Tag{ID: 9, OwnerID: 7}, nil).Once()
+		mockTags.On("Attach", mock.Anything, int64(1), int64(9)).Return(nil).Once()
+
+		resp, err := service.Execute(ctx, &models.BulkRequest{Operations: ops()})
+		require.NoError(t, err)
+		assert.Equal(t, []models.BulkItemStatus{models.BulkSucceeded, models.BulkFailed, models.BulkSucceeded, models.BulkSucceeded}, bulkStatuses(resp))
+		assert.Equal(t, 3, resp.Succeeded)
+		assert.Equal(t, 1, resp.Failed)
+		assert.ErrorIs(t, resp.Results[1].Err, services.ErrVersionMismatch)
+		assert.Equal(t, int64(3), resp.Results[2].ID)
+		assert.Equal(t, "New", resp.Results[2].Task.Title)
+		assert.False(t, runner.committed)
+		mockTasks.AssertExpectations(t)
+		mockTags.AssertExpectations(t)
+	})
+
+	t.Run("atomic batch stops at the first failure", func(t *testing.T) {
+		mockTasks := new(MockTaskRepository)
+		runner := &MockTxRunner{tasks: new(MockTaskRepository), tags: new(MockTagRepository)}
+		service := newBulkService(runner, mockTasks, new(MockTagRepository), 10)
+
+		runner.tasks.On("GetByID", mock.Anything, int64(1)).Return(open(1), nil).Once()
+		runner.tasks.On("MarkComplete", mock.Anything, int64(1), int64(2)).Return(nil).Once()
+		runner.tasks.On("Delete", mock.Anything, int64(2), int64(5)).Return(repository.ErrTaskNotFound).Once()
+
+		resp, err := service.Execute(ctx, &models.BulkRequest{Atomic: true, Operations: ops()})
+		require.NoError(t, err)
+		assert.Equal(t, []models.BulkItemStatus{models.BulkRolledBack, models.BulkFailed, models.BulkSkipped, models.BulkSkipped}, bulkStatuses(resp))
+		assert.Equal(t, 0, resp.Succeeded)
+		assert.Equal(t, 4, resp.Failed)
+		assert.ErrorIs(t, resp.Results[1].Err, services.ErrTaskNotFound)
+		assert.False(t, runner.committed)
+		runner.tasks.AssertExpectations(t)
+		mockTasks.AssertNotCalled(t, "MarkComplete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("atomic batch drops the IDs of rolled back creates", func(t *testing.T) {
+		runner := &MockTxRunner{tasks: new(MockTaskRepository), tags: new(MockTagRepository)}
+		service := newBulkService(runner, new(MockTaskRepository), new(MockTagRepository), 10)
+
+		runner.tasks.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			args.Get(1).(*models.Task).ID = 3
+		}).Return(nil).Once()
+		runner.tasks.On("GetByID", mock.Anything, int64(1)).Return(open(1), nil).Once()
+		runner.tasks.On("MarkComplete", mock.Anything, int64(1), int64(2)).Return(nil).Once()
+		runner.tasks.On("Delete", mock.Anything, int64(2), int64(0)).Return(repository.ErrTaskNotFound).Once()
+
+		resp, err := service.Execute(ctx, &models.BulkRequest{Atomic: true, Operations: []*models.BulkOperation{
+			ops()[2],
+			{Action: models.BulkComplete, ID: 1},
+			{Action: models.BulkDelete, ID: 2},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []models.BulkItemStatus{models.BulkRolledBack, models.BulkRolledBack, models.BulkFailed}, bulkStatuses(resp))
+		assert.Zero(t, resp.Results[0].ID)
+		assert.Nil(t, resp.Results[0].Task)
+		assert.Equal(t, int64(1), resp.Results[1].ID)
+		assert.False(t, runner.committed)
+	})
+
+	t.Run("atomic batch commits", func(t *testing.T) {
+		runner := &MockTxRunner{tasks: new(MockTaskRepository), tags: new(MockTagRepository)}
+		service := newBulkService(runner, new(MockTaskRepository), new(MockTagRepository), 10)
+
+		runner.tasks.On("GetByID", mock.Anything, int64(1)).Return(open(1), nil).Once()
+		runner.tasks.On("MarkComplete", mock.Anything, int64(1), int64(2)).Return(nil).Once()
+		runner.tasks.On("Delete", mock.Anything, int64(2), int64(0)).Return(nil).Once()
+
+		resp, err := service.Execute(ctx, &models.BulkRequest{Atomic: true, Operations: []*models.BulkOperation{
+			{Action: models.BulkComplete, ID: 1},
+			{Action: models.BulkDelete, ID: 2},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, 2, resp.Succeeded)
+		assert.True(t, runner.committed)
+	})
+
+	t.Run("transaction errors fail the request", func(t *testing.T) {
+		dbErr := errors.New("connection reset")
+		service := services.NewBulkService(txRunnerFunc(func() error { return dbErr }), new(MockTaskRepository), new(MockTagRepository), new(MockDependencyRepository),
+			func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) (services.TaskService, services.TagService) {
+				return services.NewTaskService(tasks), services.NewTagService(tags, tasks)
+			}, 10)
+
+		_, err := service.Execute(ctx, &models.BulkRequest{Atomic: true, Operations: []*models.BulkOperation{{Action: models.BulkDelete, ID: 2}}})
+		assert.ErrorIs(t, err, dbErr)
+	})
+
+	t.Run("invalid operations", func(t *testing.T) {
+		service := newBulkService(&MockTxRunner{}, new(MockTaskRepository), new(MockTagRepository), 10)
+
+		invalid := map[string]*models.BulkOperation{
+			"unknown action": {Action: "archive", ID: 1},
+			"missing id":     {Action: models.BulkComplete},
+			"missing task":   {Action: models.BulkCreate},
+			"unknown field":  {Action: models.BulkCreate, Task: json.RawMessage(`{"title":"x","owner_id":8}`)},
+			"missing tag id": {Action: models.BulkTag, ID: 1},
+			"malformed task": {Action: models.BulkUpdate, ID: 1, Task: json.RawMessage(`"x"`)},
+		}
+		for name, op := range invalid {
+			t.Run(name, func(t *testing.T) {
+				resp, err := service.Execute(ctx, &models.BulkRequest{Operations: []*models.BulkOperation{op}})
+				require.NoError(t, err)
+				assert.Equal(t, models.BulkFailed, resp.Results[0].Status)
+				assert.ErrorIs(t, resp.Results[0].Err, services.ErrInvalidInput)
+			})
+		}
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		service := newBulkService(&MockTxRunner{}, new(MockTaskRepository), new(MockTagRepository), 3)
+
+		_, err := service.Execute(ctx, &models.BulkRequest{Operations: ops()})
+		assert.ErrorIs(t, err, services.ErrBatchTooLarge)
+
+		_, err = service.Execute(ctx, &models.BulkRequest{})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+}
+
+// txRunnerFunc fails to open a transaction with the error it returns.
+type txRunnerFunc func() error
+
+func (f txRunnerFunc) WithinTx(ctx context.Context, fn func(tasks repository.TaskRepository, tags repository.TagRepository, deps repository.DependencyRepository) error) error {
+	return f()
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/validation"
+	"unicode/utf8"
+)
+
+var (
+	ErrCommentNotFound  = errors.New("comment not found")
+	ErrNotCommentAuthor = errors.New("only the author can change a comment")
+)
+
+const maxCommentLength = 10000
+
+type CommentService interface {
+	AddComment(ctx context.Context, taskID int64, req *models.CreateCommentRequest) (*models.Comment, error)
+	GetComments(ctx context.Context, taskID int64, limit, offset int) ([]*models.Comment, int, error)
+	UpdateComment(ctx context.Context, taskID, commentID int64, req *models.UpdateCommentRequest) (*models.Comment, error)
+	DeleteComment(ctx context.Context, taskID, commentID int64) error
+}
+
+type commentService struct {
+	repo  repository.CommentRepository
+	tasks repository.TaskRepository
+}
+
+func NewCommentService(repo repository.CommentRepository, tasks repository.TaskRepository) CommentService {
+	return &commentService{repo: repo, tasks: tasks}
+}
+
+// AddComment posts a comment on a task visible to the caller, who becomes
+// its author.
+func (s *commentService) AddComment(ctx context.Context, taskID int64, req *models.CreateCommentRequest) (*models.Comment, error) {
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	comment := &models.Comment{TaskID: taskID, Body: strings.TrimSpace(req.Body)}
+	if err := checkComment(comment); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentService) GetComments(ctx context.Context, taskID int64, limit, offset int) ([]*models.Comment, int, error) {
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetByTask(ctx, taskID, limit, offset)
+}
+
+func (s *commentService) UpdateComment(ctx context.Context, taskID, commentID int64, req *models.UpdateCommentRequest) (*models.Comment, error) {
+	comment, err := s.getOwnComment(ctx, taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Body = strings.TrimSpace(req.Body)
+	if err := checkComment(comment); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Update(ctx, comment)
+	if err != nil {
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, taskID, commentID int64) error {
+	if _, err := s.getOwnComment(ctx, taskID, commentID); err != nil {
+		return err
+	}
+
+	err := s.repo.Delete(ctx, commentID)
+	if errors.Is(err, repository.ErrCommentNotFound) {
+		return ErrCommentNotFound
+	}
+	return err
+}
+
+func (s *commentService) checkTask(ctx context.Context, taskID int64) error {
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// getOwnComment loads a comment of a visible task and makes sure the caller
+// wrote it. Admins see every task but still cannot rewrite other people's
+// words.
+func (s *commentService) getOwnComment(ctx context.Context, taskID, commentID int64) (*models.Comment, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, repository.ErrNoCaller
+	}
+
+	if err := s.checkTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+
+	comment, err := s.repo.GetByID(ctx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.TaskID != taskID {
+		return nil, ErrCommentNotFound
+	}
+	if comment.AuthorID != p.UserID {
+		return nil, ErrNotCommentAuthor
+	}
+
+	return comment, nil
+}
+
+// checkComment returns the field errors of a comment about to be stored,
+// wrapped in ErrInvalidInput.
+func checkComment(comment *models.Comment) error {
+	var errs validation.Errors
+	switch {
+	case comment.Body == "":
+		errs.Add("body", validation.CodeRequired, "is required")
+	case utf8.RuneCountInString(comment.Body) > maxCommentLength:
+		errs.Add("body", validation.CodeTooLong, fmt.Sprintf("must be at most %d characters", maxCommentLength))
+	}
+	if err := errs.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCommentRepository struct {
+	mock.Mock
+}
+
+func (m *MockCommentRepository) Create(ctx context.Context, comment *models.Comment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepository) GetByID(ctx context.Context, id int64) (*models.Comment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Comment), args.Error(1)
+}
+
+func (m *MockCommentRepository) GetByTask(ctx context.Context, taskID int64, limit, offset int) ([]*models.Comment, int, error) {
+	args := m.Called(ctx, taskID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.Comment), args.Int(1), args.Error(2)
+}
+
+func (m *MockCommentRepository) Update(ctx context.Context, comment *models.Comment) error {
+	args := m.Called(ctx, comment)
+	return args.Error(0)
+}
+
+func (m *MockCommentRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestCommentServiceMethods(t *testing.T) {
+	mockRepo := new(MockCommentRepository)
+	mockTasks := new(MockTaskRepository)
+	service := services.NewCommentService(mockRepo, mockTasks)
+
+	author := auth.NewContext(context.Background(), &auth.Principal{UserID: 7, Username: "alice", Role: models.RoleMember})
+	admin := auth.NewContext(context.Background(), &auth.Principal{UserID: 1, Username: "root", Role: models.RoleAdmin})
+	task := &models.Task{ID: 1, OwnerID: 7}
+	comment := func() *models.Comment {
+		return &models.Comment{ID: 5, TaskID: 1, AuthorID: 7, Body: "first"}
+	}
+
+	t.Run("AddComment", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(c *models.Comment) bool {
+			return c.TaskID == 1 && c.Body == "looks good"
+		})).Return(nil).Once()
+
+		_, err := service.AddComment(author, 1, &models.CreateCommentRequest{Body: "  looks good "})
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("AddComment invalid body", func(t *testing.T) {
+		for name, body := range map[string]string{"empty": "   ", "too long": strings.Repeat("x", 10001)} {
+			t.Run(name, func(t *testing.T) {
+				mockTasks.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+
+				_, err := service.AddComment(author, 1, &models.CreateCommentRequest{Body: body})
+				assert.ErrorIs(t, err, services.ErrInvalidInput)
+			})
+		}
+	})
+
+	t.Run("AddComment hidden task", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(2)).Return(nil, nil).Once()
+
+		_, err := service.AddComment(author, 2, &models.CreateCommentRequest{Body: "hi"})
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	})
+
+	t.Run("UpdateComment by author", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(5)).Return(comment(), nil).Once()
+		mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Comment")).Return(nil).Once()
+
+		updated, err := service.UpdateComment(author, 1, 5, &models.UpdateCommentRequest{Body: "second"})
+		assert.NoError(t, err)
+		assert.Equal(t, "second", updated.Body)
+	})
+
+	t.Run("UpdateComment by someone else", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(5)).Return(comment(), nil).Once()
+
+		_, err := service.UpdateComment(admin, 1, 5, &models.UpdateCommentRequest{Body: "rewritten"})
+		assert.ErrorIs(t, err, services.ErrNotCommentAuthor)
+	})
+
+	t.Run("DeleteComment on another task", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(3)).Return(&models.Task{ID: 3, OwnerID: 7}, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(5)).Return(comment(), nil).Once()
+
+		err := service.DeleteComment(author, 3, 5)
+		assert.ErrorIs(t, err, services.ErrCommentNotFound)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("DeleteComment", func(t *testing.T) {
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(5)).Return(comment(), nil).Once()
+		mockRepo.On("Delete", mock.Anything, int64(5)).Return(nil).Once()
+
+		assert.NoError(t, service.DeleteComment(author, 1, 5))
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+)
+
+var (
+	ErrDependencyCycle   = repository.ErrDependencyCycle
+	ErrInvalidDependency = errors.New("invalid dependency")
+	ErrTaskBlocked       = errors.New("task is blocked by open tasks")
+)
+
+type DependencyService interface {
+	AddDependency(ctx context.Context, taskID, blockedByID int64) error
+	RemoveDependency(ctx context.Context, taskID, blockedByID int64) error
+	GetBlockers(ctx context.Context, taskID int64) ([]*models.Task, error)
+	GetNextTasks(ctx context.Context, limit int) ([]*models.Task, error)
+}
+
+const (
+	DefaultNextTasks = 20
+	MaxNextTasks     = 100
+	// NextTasksWindow is how many of the most urgent open tasks GetNextTasks
+	// orders.
+	NextTasksWindow = 500
+)
+
+type dependencyService struct {
+	repo  repository.DependencyRepository
+	tasks repository.TaskRepository
+}
+
+func NewDependencyService(repo repository.DependencyRepository, tasks repository.TaskRepository) DependencyService {
+	return &dependencyService{repo: repo, tasks: tasks}
+}
+
+// AddDependency records that taskID cannot start until blockedByID is done.
+// Both tasks must be visible to the caller and belong to the same owner.
+func (s *dependencyService) AddDependency(ctx context.Context, taskID, blockedByID int64) error {
+	if taskID == blockedByID {
+		return ErrDependencyCycle
+	}
+
+	task, err := s.getTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	blocker, err := s.getTask(ctx, blockedByID)
+	if err != nil {
+		return err
+	}
+	if blocker.OwnerID != task.OwnerID {
+		return ErrInvalidDependency
+	}
+
+	return s.repo.Add(ctx, taskID, blockedByID)
+}
+
+func (s *dependencyService) RemoveDependency(ctx context.Context, taskID, blockedByID int64) error {
+	if _, err := s.getTask(ctx, taskID); err != nil {
+		return err
+	}
+	return s.repo.Remove(ctx, taskID, blockedByID)
+}
+
+func (s *dependencyService) GetBlockers(ctx context.Context, taskID int64) ([]*models.Task, error) {
+	if _, err := s.getTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetBlockers(ctx, taskID)
+}
+
+// GetNextTasks returns the caller's open tasks in dependency order: a task
+// only appears after every open task blocking it. Among tasks that are free
+// to go at the same point, more urgent and earlier-due tasks come first. At
+// most limit tasks are returned, taken from the NextTasksWindow most urgent
+// open tasks.
+func (s *dependencyService) GetNextTasks(ctx context.Context, limit int) ([]*models.Task, error) {
+	tasks, err := s.repo.GetOpenTasks(ctx, NextTasksWindow)
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[int64]bool, len(tasks))
+	for _, task := range tasks {
+		open[task.ID] = true
+	}
+
+	// When the window is full, blockers outside it may still be open. Tasks
+	// waiting on one are left out rather than offered too early.
+	heldUp := make(map[int64]bool)
+	if len(tasks) == NextTasksWindow {
+		var outside []int64
+		for _, task := range tasks {
+			for _, blockerID := range task.BlockedBy {
+				if !open[blockerID] {
+					outside = append(outside, blockerID)
+				}
+			}
+		}
+		stillOpen, err := s.repo.OpenTaskIDs(ctx, outside)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range stillOpen {
+			heldUp[id] = true
+		}
+	}
+
+	// Kahn's algorithm over the edges between open tasks; finished blockers
+	// no longer hold anything up.
+	pending := make(map[int64]int, len(tasks))
+	unblocks := make(map[int64][]*models.Task)
+	var ready []*models.Task
+	for _, task := range tasks {
+		for _, blockerID := range task.BlockedBy {
+			if open[blockerID] {
+				pending[task.ID]++
+				unblocks[blockerID] = append(unblocks[blockerID], task)
+			} else if heldUp[blockerID] {
+				pending[task.ID]++
+			}
+		}
+		if pending[task.ID] == 0 {
+			ready = append(ready, task)
+		}
+	}
+
+	ordered := make([]*models.Task, 0, limit)
+	for len(ready) > 0 && len(ordered) < limit {
+		sort.SliceStable(ready, func(i, j int) bool { return workBefore(ready[i], ready[j]) })
+		next := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, next)
+
+		for _, task := range unblocks[next.ID] {
+			pending[task.ID]--
+			if pending[task.ID] == 0 {
+				ready = append(ready, task)
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+func (s *dependencyService) getTask(ctx context.Context, id int64) (*models.Task, error) {
+	task, err := s.tasks.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	return task, nil
+}
+
+var priorityRank = map[models.Priority]int{
+	models.PriorityUrgent: 0,
+	models.PriorityHigh:   1,
+	models.PriorityMedium: 2,
+	models.PriorityLow:    3,
+}
+
+// workBefore orders tasks that are equally unblocked: by priority, then due
+// date with undated tasks last, then ID.
+func workBefore(a, b *models.Task) bool {
+	if pa, pb := priorityRank[a.Priority], priorityRank[b.Priority]; pa != pb {
+		return pa < pb
+	}
+	if a.DueDate == nil || b.DueDate == nil {
+		if a.DueDate != b.DueDate {
+			return b.DueDate == nil
+		}
+	} else if !a.DueDate.Equal(*b.DueDate) {
+		return a.DueDate.Before(*b.DueDate)
+	}
+	return a.ID < b.ID
+}
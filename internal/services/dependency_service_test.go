@@ -0,0 +1,159 @@
+package services_test
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"task-manager/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDependencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockDependencyRepository) Add(ctx context.Context, taskID, blockedByID int64) error {
+	args := m.Called(ctx, taskID, blockedByID)
+	return args.Error(0)
+}
+
+func (m *MockDependencyRepository) Remove(ctx context.Context, taskID, blockedByID int64) error {
+	args := m.Called(ctx, taskID, blockedByID)
+	return args.Error(0)
+}
+
+func (m *MockDependencyRepository) GetBlockers(ctx context.Context, taskID int64) ([]*models.Task, error) {
+	args := m.Called(ctx, taskID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Task), args.Error(1)
+}
+
+func (m *MockDependencyRepository) GetOpenTasks(ctx context.Context, limit int) ([]*models.Task, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Task), args.Error(1)
+}
+
+func (m *MockDependencyRepository) OpenTaskIDs(ctx context.Context, ids []int64) ([]int64, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func TestDependencyServiceMethods(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("AddDependency", func(t *testing.T) {
+		mockDeps := new(MockDependencyRepository)
+		mockTasks := new(MockTaskRepository)
+		service := services.NewDependencyService(mockDeps, mockTasks)
+
+		mockTasks.On("GetByID", mock.Anything, int64(2)).Return(&models.Task{ID: 2, OwnerID: 7}, nil).Once()
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, OwnerID: 7}, nil).Once()
+		mockDeps.On("Add", mock.Anything, int64(2), int64(1)).Return(nil).Once()
+
+		assert.NoError(t, service.AddDependency(ctx, 2, 1))
+		mockTasks.AssertExpectations(t)
+		mockDeps.AssertExpectations(t)
+	})
+
+	t.Run("AddDependency on itself", func(t *testing.T) {
+		service := services.NewDependencyService(new(MockDependencyRepository), new(MockTaskRepository))
+
+		err := service.AddDependency(ctx, 1, 1)
+		assert.ErrorIs(t, err, services.ErrDependencyCycle)
+	})
+
+	t.Run("AddDependency cycle", func(t *testing.T) {
+		mockDeps := new(MockDependencyRepository)
+		mockTasks := new(MockTaskRepository)
+		service := services.NewDependencyService(mockDeps, mockTasks)
+
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, OwnerID: 7}, nil).Once()
+		mockTasks.On("GetByID", mock.Anything, int64(3)).Return(&models.Task{ID: 3, OwnerID: 7}, nil).Once()
+		mockDeps.On("Add", mock.Anything, int64(1), int64(3)).Return(services.ErrDependencyCycle).Once()
+
+		err := service.AddDependency(ctx, 1, 3)
+		assert.ErrorIs(t, err, services.ErrDependencyCycle)
+		mockDeps.AssertExpectations(t)
+	})
+
+	t.Run("AddDependency across owners", func(t *testing.T) {
+		mockDeps := new(MockDependencyRepository)
+		mockTasks := new(MockTaskRepository)
+		service := services.NewDependencyService(mockDeps, mockTasks)
+
+		mockTasks.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, OwnerID: 7}, nil).Once()
+		mockTasks.On("GetByID", mock.Anything, int64(2)).Return(&models.Task{ID: 2, OwnerID: 8}, nil).Once()
+
+		err := service.AddDependency(ctx, 1, 2)
+		assert.ErrorIs(t, err, services.ErrInvalidDependency)
+		mockDeps.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetNextTasks", func(t *testing.T) {
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewDependencyService(mockDeps, new(MockTaskRepository))
+
+		soon := time.Now().Add(time.Hour)
+		later := soon.Add(24 * time.Hour)
+		// 4 is blocked by 2, which is blocked by 1; 5 is blocked by a task
+		// that is already finished and so not in the open set.
+		mockDeps.On("GetOpenTasks", mock.Anything, services.NextTasksWindow).Return([]*models.Task{
+			{ID: 1, Priority: models.PriorityLow, DueDate: &later},
+			{ID: 2, Priority: models.PriorityUrgent, BlockedBy: []int64{1}},
+			{ID: 3, Priority: models.PriorityMedium, DueDate: &soon},
+			{ID: 4, Priority: models.PriorityUrgent, BlockedBy: []int64{2}},
+			{ID: 5, Priority: models.PriorityMedium, DueDate: &later, BlockedBy: []int64{99}},
+		}, nil).Once()
+
+		tasks, err := service.GetNextTasks(ctx, 10)
+		assert.NoError(t, err)
+
+		ids := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		assert.Equal(t, []int64{3, 5, 1, 2, 4}, ids)
+		mockDeps.AssertNotCalled(t, "OpenTaskIDs", mock.Anything, mock.Anything)
+
+		mockDeps.On("GetOpenTasks", mock.Anything, services.NextTasksWindow).Return([]*models.Task{{ID: 1}, {ID: 2}, {ID: 3}}, nil).Once()
+		tasks, err = service.GetNextTasks(ctx, 2)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 2)
+	})
+
+	t.Run("GetNextTasks with a full window", func(t *testing.T) {
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewDependencyService(mockDeps, new(MockTaskRepository))
+
+		// Task 1 waits on a finished task and task 2 on an open one, both
+		// outside the window.
+		window := make([]*models.Task, services.NextTasksWindow)
+		for i := range window {
+			window[i] = &models.Task{ID: int64(i + 1), Priority: models.PriorityMedium}
+		}
+		window[0].BlockedBy = []int64{9001}
+		window[1].BlockedBy = []int64{9002}
+		mockDeps.On("GetOpenTasks", mock.Anything, services.NextTasksWindow).Return(window, nil).Once()
+		mockDeps.On("OpenTaskIDs", mock.Anything, []int64{9001, 9002}).Return([]int64{9002}, nil).Once()
+
+		tasks, err := service.GetNextTasks(ctx, 3)
+		assert.NoError(t, err)
+		ids := make([]int64, 0, len(tasks))
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		assert.Equal(t, []int64{1, 3, 4}, ids)
+		mockDeps.AssertExpectations(t)
+	})
+}
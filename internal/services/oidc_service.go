@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/oidc"
+	"task-manager/internal/repository"
+	"time"
+)
+
+var (
+	ErrOIDCLoginFailed = errors.New("single sign-on failed")
+)
+
+const (
+	// OIDCLoginTTL is how long a login started with BeginLogin can be
+	// completed.
+	OIDCLoginTTL = 10 * time.Minute
+	// provisionAttempts bounds how many suffixed usernames are tried when the
+	// provider's preferred username is already taken locally.
+	provisionAttempts = 5
+)
+
+// OIDCProvider is the part of *oidc.Provider the service depends on.
+type OIDCProvider interface {
+	Issuer() string
+	AuthCodeURL(state, nonce, codeChallenge string) string
+	Exchange(ctx context.Context, code, codeVerifier string) (string, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*oidc.IDTokenClaims, error)
+}
+
+type OIDCService interface {
+	BeginLogin(ctx context.Context) (authURL, state string, err error)
+	CompleteLogin(ctx context.Context, state, code string) (*models.TokenPair, error)
+}
+
+type oidcService struct {
+	provider   OIDCProvider
+	identities repository.IdentityRepository
+	users      repository.UserRepository
+	tokens     TokenService
+}
+
+func NewOIDCService(provider OIDCProvider, identities repository.IdentityRepository, users repository.UserRepository, tokens TokenService) OIDCService {
+	return &oidcService{provider: provider, identities: identities, users: users, tokens: tokens}
+}
+
+// BeginLogin records a new state, nonce and PKCE verifier and returns the
+// provider URL to redirect the user to, along with the state. The caller
+// must bind the state to the browser starting the login, so that a callback
+// URL cannot be handed to someone else to complete.
+func (s *oidcService) BeginLogin(ctx context.Context) (string, string, error) {
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.identities.SaveLoginState(ctx, &models.OIDCLoginState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.provider.AuthCodeURL(state, nonce, challenge), state, nil
+}
+
+// CompleteLogin finishes the flow started by BeginLogin and issues the same
+// application tokens as a password login.
+func (s *oidcService) CompleteLogin(ctx context.Context, state, code string) (*models.TokenPair, error) {
+	pending, err := s.identities.ConsumeLoginState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || time.Now().After(pending.ExpiresAt) {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	rawIDToken, err := s.provider.Exchange(ctx, code, pending.CodeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange failed: %v", err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	claims, err := s.provider.VerifyIDToken(ctx, rawIDToken, pending.Nonce)
+	if err != nil {
+		log.Printf("OIDC id token rejected: %v", err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.userForClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokens.IssueTokens(ctx, user)
+}
+
+// userForClaims returns the user linked to the ID token's subject, creating
+// one on first login. Identities are matched on issuer and subject only, never
+// on email, so an SSO account cannot take over an existing local account.
+func (s *oidcService) userForClaims(ctx context.Context, claims *oidc.IDTokenClaims) (*models.User, error) {
+	issuer := s.provider.Issuer()
+
+	identity, err := s.identities.GetIdentity(ctx, issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return s.linkedUser(ctx, identity)
+	}
+
+	user, err := s.provisionUser(ctx, usernameFromClaims(claims))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.identities.CreateIdentity(ctx, &models.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+	if errors.Is(err, repository.ErrIdentityExists) {
+		// A concurrent first login won the race; use the user it linked.
+		identity, err = s.identities.GetIdentity(ctx, issuer, claims.Subject)
+		if err != nil {
+			return nil, err
+		}
+		return s.linkedUser(ctx, identity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *oidcService) linkedUser(ctx context.Context, identity *models.UserIdentity) (*models.User, error) {
+	if identity == nil {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.users.GetByID(ctx, identity.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrOIDCLoginFailed
+	}
+	return user, nil
+}
+
+// provisionUser creates a member account without a password.
+func (s *oidcService) provisionUser(ctx context.Context, base string) (*models.User, error) {
+	for attempt := 0; attempt < provisionAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := oidc.RandomString(3)
+			if err != nil {
+				return nil, err
+			}
+			username = fmt.Sprintf("%s-%s", truncate(base, maxUsernameLength-5), suffix)
+		}
+
+		user := &models.User{Username: username, Role: models.RoleMember}
+		err := s.users.Create(ctx, user)
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	return nil, ErrUsernameTaken
+}
+
+func usernameFromClaims(claims *oidc.IDTokenClaims) string {
+	username := strings.TrimSpace(claims.PreferredUsername)
+	if username == "" && claims.Email != "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if username == "" {
+		username = "sso-" + claims.Subject
+	}
+	return truncate(username, maxUsernameLength)
+}
+
+// truncate cuts s to at most n characters, never inside one.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
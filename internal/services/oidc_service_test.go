@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/oidc"
+	"task-manager/internal/oidc/oidctest"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockIdentityRepository struct {
+	mock.Mock
+	states map[string]*models.OIDCLoginState
+}
+
+func (m *MockIdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	args := m.Called(ctx, issuer, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserIdentity), args.Error(1)
+}
+
+func (m *MockIdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+// Login states are kept in memory so tests can drive the full redirect flow.
+func (m *MockIdentityRepository) SaveLoginState(ctx context.Context, state *models.OIDCLoginState) error {
+	m.states[state.State] = state
+	return nil
+}
+
+func (m *MockIdentityRepository) ConsumeLoginState(ctx context.Context, state string) (*models.OIDCLoginState, error) {
+	s := m.states[state]
+	delete(m.states, state)
+	return s, nil
+}
+
+func TestOIDCServiceMethods(t *testing.T) {
+	idp, err := oidctest.NewServer("task-manager", "s3cret")
+	require.NoError(t, err)
+	defer idp.Close()
+
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "task-manager",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8080/oidc/callback",
+	})
+	require.NoError(t, err)
+
+	key, err := auth.GenerateEd25519Key("test")
+	require.NoError(t, err)
+	keys, err := auth.NewKeySet(key.ID, key)
+	require.NoError(t, err)
+
+	mockIdentities := &MockIdentityRepository{states: map[string]*models.OIDCLoginState{}}
+	mockUsers := new(MockUserRepository)
+	mockTokens := new(MockTokenRepository)
+	tokens := services.NewTokenService(mockTokens, mockUsers, keys)
+	service := services.NewOIDCService(provider, mockIdentities, mockUsers, tokens)
+
+	login := func(t *testing.T) (string, string) {
+		authURL, started, err := service.BeginLogin(context.Background())
+		require.NoError(t, err)
+
+		u, err := url.Parse(authURL)
+		require.NoError(t, err)
+		assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+
+		code, state, err := idp.Authorize(authURL)
+		require.NoError(t, err)
+		assert.Equal(t, started, state)
+		return state, code
+	}
+
+	t.Run("CompleteLogin provisions user on first login", func(t *testing.T) {
+		idp.SetIdentity(oidctest.Identity{Subject: "sub-1", Email: "ada@example.com", PreferredUsername: "ada"})
+		state, code := login(t)
+
+		mockIdentities.On("GetIdentity", mock.Anything, idp.Issuer(), "sub-1").Return(nil, nil).Once()
+		mockUsers.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == "ada"
+		})).Return(repository.ErrUsernameTaken).Once()
+		mockUsers.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username != "ada" && u.Role == models.RoleMember && u.PasswordHash == ""
+		})).Run(func(args mock.Arguments) { args.Get(1).(*models.User).ID = 42 }).Return(nil).Once()
+		mockIdentities.On("CreateIdentity", mock.Anything, mock.MatchedBy(func(i *models.UserIdentity) bool {
+			return i.UserID == 42 && i.Subject == "sub-1" && i.Email == "ada@example.com"
+		})).Return(nil).Once()
+		mockTokens.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil).Once()
+
+		pair, err := service.CompleteLogin(context.Background(), state, code)
+		require.NoError(t, err)
+		assert.NotEmpty(t, pair.AccessToken)
+
+		mockIdentities.AssertExpectations(t)
+		mockUsers.AssertExpectations(t)
+		mockTokens.AssertExpectations(t)
+	})
+
+	t.Run("CompleteLogin truncates long usernames by character", func(t *testing.T) {
+		idp.SetIdentity(oidctest.Identity{Subject: "sub-3", PreferredUsername: strings.Repeat("é", 60)})
+		state, code := login(t)
+
+		mockIdentities.On("GetIdentity", mock.Anything, idp.Issuer(), "sub-3").Return(nil, nil).Once()
+		mockUsers.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return u.Username == strings.Repeat("é", 50)
+		})).Run(func(args mock.Arguments) { args.Get(1).(*models.User).ID = 43 }).Return(nil).Once()
+		mockIdentities.On("CreateIdentity", mock.Anything, mock.Anything).Return(nil).Once()
+		mockTokens.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := service.CompleteLogin(context.Background(), state, code)
+		require.NoError(t, err)
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("CompleteLogin uses linked user", func(t *testing.T) {
+		idp.SetIdentity(oidctest.Identity{Subject: "sub-2", PreferredUsername: "grace"})
+		state, code := login(t)
+
+		identity := &models.UserIdentity{UserID: 9, Issuer: idp.Issuer(), Subject: "sub-2"}
+		mockIdentities.On("GetIdentity", mock.Anything, idp.Issuer(), "sub-2").Return(identity, nil).Once()
+		mockUsers.On("GetByID", mock.Anything, int64(9)).Return(&models.User{ID: 9, Username: "grace", Role: models.RoleAdmin}, nil).Once()
+		mockTokens.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := service.CompleteLogin(context.Background(), state, code)
+		require.NoError(t, err)
+
+		mockIdentities.AssertExpectations(t)
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("CompleteLogin rejects replayed state", func(t *testing.T) {
+		idp.SetIdentity(oidctest.Identity{Subject: "sub-2"})
+		state, code := login(t)
+
+		identity := &models.UserIdentity{UserID: 9, Issuer: idp.Issuer(), Subject: "sub-2"}
+		mockIdentities.On("GetIdentity", mock.Anything, idp.Issuer(), "sub-2").Return(identity, nil).Once()
+		mockUsers.On("GetByID", mock.Anything, int64(9)).Return(&models.User{ID: 9}, nil).Once()
+		mockTokens.On("CreateRefreshToken", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := service.CompleteLogin(context.Background(), state, code)
+		require.NoError(t, err)
+
+		_, err = service.CompleteLogin(context.Background(), state, code)
+		assert.ErrorIs(t, err, services.ErrOIDCLoginFailed)
+	})
+
+	t.Run("CompleteLogin rejects unknown state", func(t *testing.T) {
+		_, code := login(t)
+
+		_, err := service.CompleteLogin(context.Background(), "forged", code)
+		assert.ErrorIs(t, err, services.ErrOIDCLoginFailed)
+	})
+}
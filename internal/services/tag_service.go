@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/validation"
+)
+
+var (
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exists")
+)
+
+const (
+	defaultTagColor  = "#808080"
+	maxTagNameLength = 50
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type TagService interface {
+	CreateTag(ctx context.Context, req *models.CreateTagRequest) (*models.Tag, error)
+	GetAllTags(ctx context.Context) ([]*models.Tag, error)
+	UpdateTag(ctx context.Context, id int64, req *models.UpdateTagRequest) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id int64) error
+	AttachTag(ctx context.Context, taskID, tagID int64) error
+	DetachTag(ctx context.Context, taskID, tagID int64) error
+}
+
+type tagService struct {
+	repo  repository.TagRepository
+	tasks repository.TaskRepository
+}
+
+func NewTagService(repo repository.TagRepository, tasks repository.TaskRepository) TagService {
+	return &tagService{repo: repo, tasks: tasks}
+}
+
+func (s *tagService) CreateTag(ctx context.Context, req *models.CreateTagRequest) (*models.Tag, error) {
+	tag := &models.Tag{
+		Name:  strings.TrimSpace(req.Name),
+		Color: req.Color,
+	}
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if err := checkTag(tag); err != nil {
+		return nil, err
+	}
+
+	err := s.repo.Create(ctx, tag)
+	if err != nil {
+		if errors.Is(err, repository.ErrTagExists) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (s *tagService) GetAllTags(ctx context.Context) ([]*models.Tag, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *tagService) UpdateTag(ctx context.Context, id int64, req *models.UpdateTagRequest) (*models.Tag, error) {
+	tag, err := s.getTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		tag.Name = name
+	}
+	if req.Color != "" {
+		tag.Color = req.Color
+	}
+	if err := checkTag(tag); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.Update(ctx, tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTagExists):
+			return nil, ErrTagExists
+		case errors.Is(err, repository.ErrTagNotFound):
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, id int64) error {
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrTagNotFound) {
+		return ErrTagNotFound
+	}
+	return err
+}
+
+// AttachTag adds a tag to a task. Both must be visible to the caller, and the
+// tag must belong to the task's owner so one user's labels never show up on
+// another user's tasks.
+func (s *tagService) AttachTag(ctx context.Context, taskID, tagID int64) error {
+	if err := s.checkTaskAndTag(ctx, taskID, tagID); err != nil {
+		return err
+	}
+	return s.repo.Attach(ctx, taskID, tagID)
+}
+
+func (s *tagService) DetachTag(ctx context.Context, taskID, tagID int64) error {
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrTaskNotFound
+	}
+	return s.repo.Detach(ctx, taskID, tagID)
+}
+
+func (s *tagService) checkTaskAndTag(ctx context.Context, taskID, tagID int64) error {
+	task, err := s.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task == nil {
+		return ErrTaskNotFound
+	}
+
+	tag, err := s.getTag(ctx, tagID)
+	if err != nil {
+		return err
+	}
+	if tag.OwnerID != task.OwnerID {
+		return ErrTagNotFound
+	}
+
+	return nil
+}
+
+func (s *tagService) getTag(ctx context.Context, id int64) (*models.Tag, error) {
+	tag, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// checkTag returns the field errors of a tag about to be stored, wrapped in
+// ErrInvalidInput.
+func checkTag(tag *models.Tag) error {
+	var errs validation.Errors
+	switch {
+	case tag.Name == "":
+		errs.Add("name", validation.CodeRequired, "is required")
+	case len(tag.Name) > maxTagNameLength:
+		errs.Add("name", validation.CodeTooLong, fmt.Sprintf("must be at most %d bytes", maxTagNameLength))
+	}
+	if !tagColorPattern.MatchString(tag.Color) {
+		errs.Add("color", validation.CodeInvalid, "must be a hex color such as #808080")
+	}
+	if err := errs.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services_test
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) GetByID(ctx context.Context, id int64) (*models.Tag, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) GetAll(ctx context.Context) ([]*models.Tag, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Attach(ctx context.Context, taskID, tagID int64) error {
+	args := m.Called(ctx, taskID, tagID)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Detach(ctx context.Context, taskID, tagID int64) error {
+	args := m.Called(ctx, taskID, tagID)
+	return args.Error(0)
+}
+
+func TestTagServiceMethods(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	mockTasks := new(MockTaskRepository)
+	service := services.NewTagService(mockRepo, mockTasks)
+
+	t.Run("CreateTag default color", func(t *testing.T) {
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Tag")).Return(nil).Once()
+
+		tag, err := service.CreateTag(context.Background(), &models.CreateTagRequest{Name: " backend "})
+		assert.NoError(t, err)
+		assert.Equal(t, "backend", tag.Name)
+		assert.Equal(t, "#808080", tag.Color)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateTag invalid color", func(t *testing.T) {
+		tag, err := service.CreateTag(context.Background(), &models.CreateTagRequest{Name: "x", Color: "red"})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+		assert.Nil(t, tag)
+	})
+
+	t.Run("CreateTag duplicate", func(t *testing.T) {
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Tag")).Return(repository.ErrTagExists).Once()
+
+		_, err := service.CreateTag(context.Background(), &models.CreateTagRequest{Name: "backend"})
+		assert.ErrorIs(t, err, services.ErrTagExists)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTag rename and recolor", func(t *testing.T) {
+		tag := &models.Tag{ID: 4, Name: "old", Color: "#000000"}
+		mockRepo.On("GetByID", mock.Anything, int64(4)).Return(tag, nil).Once()
+		mockRepo.On("Update", mock.Anything, tag).Return(nil).Once()
+
+		got, err := service.UpdateTag(context.Background(), 4, &models.UpdateTagRequest{Name: "new", Color: "#ff0000"})
+		assert.NoError(t, err)
+		assert.Equal(t, "new", got.Name)
+		assert.Equal(t, "#ff0000", got.Color)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("AttachTag", func(t *testing.T) {
+		testCases := map[string]struct {
+			task    *models.Task
+			tag     *models.Tag
+			wantErr error
+		}{
+			"same owner":     {task: &models.Task{ID: 1, OwnerID: 7}, tag: &models.Tag{ID: 2, OwnerID: 7}},
+			"task not found": {task: nil, wantErr: services.ErrTaskNotFound},
+			"tag not found":  {task: &models.Task{ID: 1, OwnerID: 7}, tag: nil, wantErr: services.ErrTagNotFound},
+			"other owner":    {task: &models.Task{ID: 1, OwnerID: 7}, tag: &models.Tag{ID: 2, OwnerID: 8}, wantErr: services.ErrTagNotFound},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockTasks.On("GetByID", mock.Anything, int64(1)).Return(tc.task, nil).Once()
+				if tc.task != nil {
+					mockRepo.On("GetByID", mock.Anything, int64(2)).Return(tc.tag, nil).Once()
+				}
+				if tc.wantErr == nil {
+					mockRepo.On("Attach", mock.Anything, int64(1), int64(2)).Return(nil).Once()
+				}
+
+				err := service.AttachTag(context.Background(), 1, 2)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+				} else {
+					assert.NoError(t, err)
+				}
+				mockRepo.AssertExpectations(t)
+				mockTasks.AssertExpectations(t)
+			})
+		}
+	})
+}
@@ -1,46 +1,217 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"task-manager/internal/auth"
+	"task-manager/internal/jsonpatch"
 	"task-manager/internal/models"
+	"task-manager/internal/recurrence"
 	"task-manager/internal/repository"
+	"task-manager/internal/validation"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
-	ErrTaskNotFound = errors.New("task not found")
-	ErrInvalidInput = errors.New("invalid input")
+	// ErrTaskNotFound is shared with the repository so callers can match
+	// either layer's not-found error with errors.Is.
+	ErrTaskNotFound      = repository.ErrTaskNotFound
+	ErrInvalidInput      = errors.New("invalid input")
+	ErrInvalidTransition = errors.New("invalid status transition")
+	ErrInvalidParent     = errors.New("invalid parent task")
+	ErrParentCycle       = errors.New("parent would create a cycle")
+	ErrHasChildren       = errors.New("task has subtasks")
+	ErrOpenChildren      = errors.New("task has open subtasks")
+	ErrNotRecurring      = errors.New("task does not recur")
+	ErrInvalidCursor     = repository.ErrInvalidCursor
+	ErrVersionMismatch   = repository.ErrVersionMismatch
+	// ErrNoCaller is returned when the context carries no authenticated
+	// caller to scope the request to.
+	ErrNoCaller = repository.ErrNoCaller
+	// ErrInvalidPatch covers malformed patches and ones that leave the task
+	// in a state that is not a valid update.
+	ErrInvalidPatch = jsonpatch.ErrInvalidPatch
+	// ErrPatchTestFailed is returned when a JSON Patch "test" operation does
+	// not hold.
+	ErrPatchTestFailed = jsonpatch.ErrTestFailed
 )
 
+const (
+	DefaultOccurrencePreview = 5
+	MaxOccurrencePreview     = 50
+)
+
+const (
+	maxSearchLength = 200
+	maxSearchTerms  = 10
+)
+
+// ChildDeletePolicy decides what happens to subtasks when their parent is
+// deleted.
+type ChildDeletePolicy string
+
+const (
+	// DeleteOrphan keeps the subtasks and turns them into top-level tasks.
+	DeleteOrphan ChildDeletePolicy = "orphan"
+	// DeleteCascade deletes the whole subtree.
+	DeleteCascade ChildDeletePolicy = "cascade"
+	// DeleteRestrict refuses to delete a task that still has subtasks.
+	DeleteRestrict ChildDeletePolicy = "restrict"
+)
+
+// ChildCompletePolicy decides what happens to open subtasks when their
+// parent is completed.
+type ChildCompletePolicy string
+
+const (
+	// CompleteIgnore completes the parent and leaves subtasks as they are.
+	CompleteIgnore ChildCompletePolicy = "ignore"
+	// CompleteCascade completes every open task in the subtree.
+	CompleteCascade ChildCompletePolicy = "cascade"
+	// CompleteRestrict refuses to complete a task with open subtasks.
+	CompleteRestrict ChildCompletePolicy = "restrict"
+)
+
+type HierarchyPolicy struct {
+	OnDelete   ChildDeletePolicy
+	OnComplete ChildCompletePolicy
+}
+
+// DefaultHierarchyPolicy matches how tasks behaved before subtasks existed:
+// deleting or completing a task never touches any other task.
+var DefaultHierarchyPolicy = HierarchyPolicy{
+	OnDelete:   DeleteOrphan,
+	OnComplete: CompleteIgnore,
+}
+
+// BlockerPolicy decides what happens when a task is started or completed
+// while tasks blocking it are still open.
+type BlockerPolicy string
+
+const (
+	// BlockersRefuse rejects the change with ErrTaskBlocked.
+	BlockersRefuse BlockerPolicy = "refuse"
+	// BlockersWarn allows the change and logs a warning.
+	BlockersWarn BlockerPolicy = "warn"
+)
+
+type TaskServiceOption func(*taskService)
+
+func WithHierarchyPolicy(policy HierarchyPolicy) TaskServiceOption {
+	return func(s *taskService) {
+		s.hierarchy = policy
+	}
+}
+
+// WithDependencies makes the service check a task's blockers before it moves
+// to in progress or done. Without it dependencies are not enforced.
+func WithDependencies(deps repository.DependencyRepository, policy BlockerPolicy) TaskServiceOption {
+	return func(s *taskService) {
+		s.deps = deps
+		s.blockers = policy
+	}
+}
+
+// WithClock makes the service read the current time from now, which the
+// checks on how far in the past a due date may be are relative to.
+func WithClock(now func() time.Time) TaskServiceOption {
+	return func(s *taskService) {
+		s.now = now
+	}
+}
+
 type TaskService interface {
 	CreateTask(ctx context.Context, req *models.CreateTaskRequest) (*models.Task, error)
 	GetTask(ctx context.Context, id int64) (*models.Task, error)
-	GetAllTasks(ctx context.Context, limit, offset int) ([]*models.Task, int, error)
-	UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest) (*models.Task, error)
-	MarkTaskComplete(ctx context.Context, id int64) error
-	DeleteTask(ctx context.Context, id int64) error
+	GetAllTasks(ctx context.Context, filter *models.TaskFilter, limit, offset int) ([]*models.Task, int, error)
+	ListTasks(ctx context.Context, filter *models.TaskFilter, cursor string, limit int, withTotal bool) (*models.TaskPage, error)
+	UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest, version int64) (*models.Task, error)
+	PatchTask(ctx context.Context, id int64, patch []byte, format models.PatchFormat, version int64) (*models.Task, error)
+	MarkTaskComplete(ctx context.Context, id, version int64) error
+	DeleteTask(ctx context.Context, id, version int64) error
 	GetDueTasks(ctx context.Context, from, to int64) ([]*models.Task, error)
+	GetChildren(ctx context.Context, id int64) ([]*models.Task, error)
+	GetSubtree(ctx context.Context, id int64) (*models.Task, error)
+	SearchTasks(ctx context.Context, query string, limit, offset int) ([]*models.TaskSearchResult, int, error)
+	GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error)
+	RestoreTask(ctx context.Context, id int64) error
+	PreviewOccurrences(ctx context.Context, id int64, n int) (*models.OccurrencesResponse, error)
+	StopRecurrence(ctx context.Context, id int64) error
 }
 
 type taskService struct {
-	repo repository.TaskRepository
+	repo      repository.TaskRepository
+	hierarchy HierarchyPolicy
+	deps      repository.DependencyRepository
+	blockers  BlockerPolicy
+	now       func() time.Time
 }
 
-func NewTaskService(repo repository.TaskRepository) TaskService {
-	return &taskService{repo: repo}
+func NewTaskService(repo repository.TaskRepository, opts ...TaskServiceOption) TaskService {
+	s := &taskService{repo: repo, hierarchy: DefaultHierarchyPolicy, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *taskService) CreateTask(ctx context.Context, req *models.CreateTaskRequest) (*models.Task, error) {
-	if req.Title == "" {
-		return nil, ErrInvalidInput
+	priority := req.Priority
+	if priority == "" {
+		priority = models.PriorityMedium
+	}
+	status := req.Status
+	if status == "" {
+		status = models.StatusTodo
 	}
 
 	task := &models.Task{
 		Title:       req.Title,
 		Description: req.Description,
-		DueDate:     req.DueDate, //UTC
-		IsCompleted: false,
+		StartDate:   req.StartDate,
+		Priority:    priority,
+		Status:      status,
+		IsCompleted: status == models.StatusDone,
+		ParentID:    req.ParentID,
+		Tags:        []*models.Tag{},
+		BlockedBy:   []int64{},
+	}
+	if !req.DueDate.IsZero() {
+		dueDate := req.DueDate //UTC
+		task.DueDate = &dueDate
+	}
+
+	errs := validation.Struct(req)
+	checkTaskFields(&errs, task, nil, s.now())
+	var rule *recurrence.Rule
+	if req.Recurrence != "" {
+		var err error
+		if rule, err = recurrence.Parse(req.Recurrence); err != nil {
+			errs.Add("recurrence", validation.CodeInvalid, err.Error())
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if req.Recurrence != "" {
+		task.Recurrence = &models.Recurrence{Rule: rule.String(), StartsAt: *task.DueDate}
+	}
+	if task.ParentID != nil {
+		p, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, repository.ErrNoCaller
+		}
+		if err := s.checkParent(ctx, *task.ParentID, p.UserID); err != nil {
+			return nil, err
+		}
 	}
 
 	err := s.repo.Create(ctx, task)
@@ -62,40 +233,377 @@ func (s *taskService) GetTask(ctx context.Context, id int64) (*models.Task, erro
 	return task, nil
 }
 
-func (s *taskService) GetAllTasks(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
-	return s.repo.GetAll(ctx, limit, offset)
+func (s *taskService) GetAllTasks(ctx context.Context, filter *models.TaskFilter, limit, offset int) ([]*models.Task, int, error) {
+	if filter == nil {
+		filter = &models.TaskFilter{}
+	}
+	if err := checkTaskFilter(filter); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetAll(ctx, filter, limit, offset)
 }
 
-func (s *taskService) UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest) (*models.Task, error) {
-	existingTask, err := s.GetTask(ctx, id)
+// ListTasks returns a page of tasks after the position encoded in cursor, or
+// the first page if cursor is empty. A cursor is only valid with the sort
+// order it was issued for. Counting every match is costly, so the total is
+// only filled in if withTotal is set.
+func (s *taskService) ListTasks(ctx context.Context, filter *models.TaskFilter, cursor string, limit int, withTotal bool) (*models.TaskPage, error) {
+	if filter == nil {
+		filter = &models.TaskFilter{}
+	}
+	if err := checkTaskFilter(filter); err != nil {
+		return nil, err
+	}
+
+	var from *models.TaskCursor
+	if cursor != "" {
+		var err error
+		from, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if from.SortBy != filter.SortBy || from.Descending != filter.Descending {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	page, err := s.repo.GetPage(ctx, filter, from, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	if req.Title != "" {
-		existingTask.Title = req.Title
+	if page.NextCursor, err = encodeCursor(page.Next); err != nil {
+		return nil, err
+	}
+	if page.PrevCursor, err = encodeCursor(page.Prev); err != nil {
+		return nil, err
+	}
+
+	if withTotal {
+		total, err := s.repo.Count(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		page.Total = &total
 	}
-	if req.Description != "" {
-		existingTask.Description = req.Description
+
+	return page, nil
+}
+
+// encodeCursor makes an opaque, URL-safe token of c, or "" if c is nil.
+func encodeCursor(c *models.TaskCursor) (string, error) {
+	if c == nil {
+		return "", nil
 	}
-	if !req.DueDate.IsZero() {
-		existingTask.DueDate = req.DueDate //UTC
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
 
-	err = s.repo.Update(ctx, existingTask)
+func decodeCursor(s string) (*models.TaskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &models.TaskCursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.ID == 0 || c.Key == nil {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// checkTaskFilter rejects filters that name unknown values or empty ranges,
+// and drops blank and repeated tag names.
+func checkTaskFilter(filter *models.TaskFilter) error {
+	if filter.SortBy == "" {
+		filter.SortBy = models.SortByCreatedAt
+	}
+	if !filter.SortBy.Valid() {
+		return ErrInvalidInput
+	}
+
+	for _, p := range filter.Priorities {
+		if !p.Valid() {
+			return ErrInvalidInput
+		}
+	}
+
+	ranges := [][2]*time.Time{
+		{filter.DueFrom, filter.DueTo},
+		{filter.CreatedFrom, filter.CreatedTo},
+		{filter.UpdatedFrom, filter.UpdatedTo},
+	}
+	for _, rg := range ranges {
+		if rg[0] != nil && rg[1] != nil && !rg[0].Before(*rg[1]) {
+			return ErrInvalidInput
+		}
+	}
+
+	seen := make(map[string]bool, len(filter.Tags))
+	tags := filter.Tags[:0]
+	for _, tag := range filter.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	filter.Tags = tags
+
+	return nil
+}
+
+// UpdateTask replaces a task's editable fields with req. A non-zero version
+// must match the task's current version, as sent in If-Match. Either way the
+// write only goes through if nobody else changed the task since it was read
+// here.
+func (s *taskService) UpdateTask(ctx context.Context, id int64, req *models.UpdateTaskRequest, version int64) (*models.Task, error) {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && task.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	return s.replaceTask(ctx, task, req)
+}
+
+// PatchTask applies a JSON Merge Patch or JSON Patch to the editable fields
+// of a task, which are those of UpdateTaskRequest, then stores the result as
+// UpdateTask would. The version is checked the same way.
+func (s *taskService) PatchTask(ctx context.Context, id int64, patch []byte, format models.PatchFormat, version int64) (*models.Task, error) {
+	task, err := s.GetTask(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	if version != 0 && task.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := json.Marshal(editableFields(task))
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case models.MergePatch:
+		doc, err = jsonpatch.Merge(doc, patch)
+	case models.JSONPatch:
+		doc, err = jsonpatch.Apply(doc, patch)
+	default:
+		return nil, ErrInvalidPatch
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The patched document must still be a valid request; a patch cannot
+	// add fields that are not editable.
+	req := &models.UpdateTaskRequest{}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	return s.replaceTask(ctx, task, req)
+}
+
+// editableFields returns the current state of a task as the request that
+// would replace it with itself.
+func editableFields(task *models.Task) *models.UpdateTaskRequest {
+	return &models.UpdateTaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		DueDate:     task.DueDate,
+		StartDate:   task.StartDate,
+		Priority:    task.Priority,
+		Status:      task.Status,
+		ParentID:    task.ParentID,
+	}
+}
+
+// replaceTask gives task the fields in req, clearing those it leaves out,
+// and stores it.
+func (s *taskService) replaceTask(ctx context.Context, task *models.Task, req *models.UpdateTaskRequest) (*models.Task, error) {
+	priority := req.Priority
+	if priority == "" {
+		priority = models.PriorityMedium
+	}
+	status := req.Status
+	if status == "" {
+		status = models.StatusTodo
+	}
+
+	next := *task
+	next.DueDate, next.StartDate = req.DueDate, req.StartDate
+	next.Priority, next.Status = priority, status
+	// As with due dates, a title or description the task already has is not
+	// checked again, so limits tightened later do not lock tasks.
+	errs := validation.Struct(req)
+	if req.Title == task.Title {
+		errs = errs.Without("title")
+	}
+	if req.Description == task.Description {
+		errs = errs.Without("description")
+	}
+	checkTaskFields(&errs, &next, task, s.now())
+	if err := errs.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if err := checkTransition(task.Status, status); err != nil {
+		return nil, err
+	}
+	if status != task.Status && (status == models.StatusInProgress || status == models.StatusDone) {
+		if err := s.checkBlockers(ctx, task.ID, nil); err != nil {
+			return nil, err
+		}
+	}
+	completing := status == models.StatusDone && task.Status != models.StatusDone
+
+	// A parent_id of 0, like null, makes the task a top-level one.
+	var parentID *int64
+	if req.ParentID != nil && *req.ParentID != 0 {
+		parentID = req.ParentID
+		if task.ParentID == nil || *task.ParentID != *parentID {
+			if err := s.checkReparent(ctx, task, *parentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	task.Title = req.Title
+	task.Description = req.Description
+	task.DueDate = req.DueDate //UTC
+	task.StartDate = req.StartDate
+	task.Priority = priority
+	task.Status = status
+	task.ParentID = parentID
+	if status == models.StatusDone && s.hierarchy.OnComplete == CompleteRestrict {
+		if err := s.checkNoOpenChildren(ctx, task.ID); err != nil {
+			return nil, err
+		}
+	}
+	cascade := status == models.StatusDone && s.hierarchy.OnComplete == CompleteCascade
+	var subtasks []*models.Task
+	if cascade {
+		var err error
+		if subtasks, err = s.checkCascade(ctx, task.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if cascade {
+		err = s.repo.UpdateTree(ctx, task)
+	} else {
+		err = s.repo.Update(ctx, task)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if completing {
+		if err := s.spawnNextOccurrence(ctx, task); err != nil {
+			return nil, err
+		}
+	}
+	for _, subtask := range subtasks {
+		if err := s.spawnNextOccurrence(ctx, subtask); err != nil {
+			return nil, err
+		}
+	}
 
-	return existingTask, nil
+	return task, nil
+}
+
+func (s *taskService) MarkTaskComplete(ctx context.Context, id, version int64) error {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version != 0 && task.Version != version {
+		return ErrVersionMismatch
+	}
+	if err := checkTransition(task.Status, models.StatusDone); err != nil {
+		return err
+	}
+	if err := s.checkBlockers(ctx, id, nil); err != nil {
+		return err
+	}
+
+	var subtasks []*models.Task
+	switch s.hierarchy.OnComplete {
+	case CompleteCascade:
+		if subtasks, err = s.checkCascade(ctx, id); err == nil {
+			err = s.repo.CompleteTree(ctx, id, task.Version)
+		}
+	case CompleteRestrict:
+		if err = s.checkNoOpenChildren(ctx, id); err == nil {
+			err = s.repo.MarkComplete(ctx, id, task.Version)
+		}
+	default:
+		err = s.repo.MarkComplete(ctx, id, task.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, completed := range append([]*models.Task{task}, subtasks...) {
+		if err := s.spawnNextOccurrence(ctx, completed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTask moves a task to the trash, from which it can be restored until
+// the purge job removes it for good. A non-zero version must match the
+// task's current version.
+func (s *taskService) DeleteTask(ctx context.Context, id, version int64) error {
+	switch s.hierarchy.OnDelete {
+	case DeleteCascade:
+		return s.repo.DeleteTree(ctx, id, version)
+	case DeleteRestrict:
+		children, err := s.repo.GetChildren(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return ErrHasChildren
+		}
+	}
+
+	return s.repo.Delete(ctx, id, version)
 }
 
-func (s *taskService) MarkTaskComplete(ctx context.Context, id int64) error {
-	return s.repo.MarkComplete(ctx, id)
+// SearchTasks finds the caller's tasks containing every word of query, each
+// possibly as the start of a longer word. Punctuation separates words and is
+// otherwise ignored.
+func (s *taskService) SearchTasks(ctx context.Context, query string, limit, offset int) ([]*models.TaskSearchResult, int, error) {
+	if utf8.RuneCountInString(query) > maxSearchLength {
+		return nil, 0, ErrInvalidInput
+	}
+
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 || len(terms) > maxSearchTerms {
+		return nil, 0, ErrInvalidInput
+	}
+
+	return s.repo.Search(ctx, terms, limit, offset)
 }
 
-func (s *taskService) DeleteTask(ctx context.Context, id int64) error {
-	return s.repo.Delete(ctx, id)
+func (s *taskService) GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+	return s.repo.GetTrash(ctx, limit, offset)
+}
+
+func (s *taskService) RestoreTask(ctx context.Context, id int64) error {
+	return s.repo.Restore(ctx, id)
 }
 
 func (s *taskService) GetDueTasks(ctx context.Context, from, to int64) ([]*models.Task, error) {
@@ -104,3 +612,263 @@ func (s *taskService) GetDueTasks(ctx context.Context, from, to int64) ([]*model
 
 	return s.repo.GetDueTasks(ctx, fromTime, toTime)
 }
+
+func (s *taskService) GetChildren(ctx context.Context, id int64) ([]*models.Task, error) {
+	if _, err := s.GetTask(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetChildren(ctx, id)
+}
+
+// GetSubtree returns the task with its descendants nested under Children.
+func (s *taskService) GetSubtree(ctx context.Context, id int64) (*models.Task, error) {
+	tasks, err := s.repo.GetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	for _, task := range tasks {
+		task.Children = []*models.Task{}
+		byID[task.ID] = task
+	}
+
+	root, ok := byID[id]
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	for _, task := range tasks {
+		if task.ID == id || task.ParentID == nil {
+			continue
+		}
+		if parent, ok := byID[*task.ParentID]; ok {
+			parent.Children = append(parent.Children, task)
+		}
+	}
+
+	return root, nil
+}
+
+// PreviewOccurrences lists up to n due dates the series of a recurring task
+// will produce after the task itself. A stopped series has none.
+func (s *taskService) PreviewOccurrences(ctx context.Context, id int64, n int) (*models.OccurrencesResponse, error) {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Recurrence == nil {
+		return nil, ErrNotRecurring
+	}
+
+	resp := &models.OccurrencesResponse{Rule: task.Recurrence.Rule, Occurrences: []time.Time{}}
+	if task.Recurrence.StoppedAt != nil || task.DueDate == nil {
+		return resp, nil
+	}
+
+	rule, err := recurrence.Parse(task.Recurrence.Rule)
+	if err != nil {
+		return nil, err
+	}
+	resp.Occurrences = append(resp.Occurrences, rule.Next(task.Recurrence.StartsAt, *task.DueDate, n)...)
+
+	return resp, nil
+}
+
+// StopRecurrence ends the series a task belongs to. Existing tasks are kept;
+// completing them just no longer creates new ones.
+func (s *taskService) StopRecurrence(ctx context.Context, id int64) error {
+	task, err := s.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+	if task.Recurrence == nil {
+		return ErrNotRecurring
+	}
+	return s.repo.StopRecurrence(ctx, task.Recurrence.ID)
+}
+
+// spawnNextOccurrence creates the task that follows a just completed task of
+// a running series. Doing so twice for the same task is harmless.
+func (s *taskService) spawnNextOccurrence(ctx context.Context, task *models.Task) error {
+	if task.Recurrence == nil || task.Recurrence.StoppedAt != nil || task.DueDate == nil {
+		return nil
+	}
+
+	rule, err := recurrence.Parse(task.Recurrence.Rule)
+	if err != nil {
+		return err
+	}
+	next := rule.Next(task.Recurrence.StartsAt, *task.DueDate, 1)
+	if len(next) == 0 {
+		return nil
+	}
+
+	occurrence := &models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		DueDate:     &next[0],
+		Priority:    task.Priority,
+		Status:      models.StatusTodo,
+		OwnerID:     task.OwnerID,
+		ParentID:    task.ParentID,
+		Tags:        task.Tags,
+		BlockedBy:   []int64{},
+		Recurrence: &models.Recurrence{
+			ID:         task.Recurrence.ID,
+			Rule:       task.Recurrence.Rule,
+			StartsAt:   task.Recurrence.StartsAt,
+			Occurrence: task.Recurrence.Occurrence + 1,
+		},
+	}
+	if task.StartDate != nil {
+		// Keep the same lead time between start and due date.
+		start := task.StartDate.Add(next[0].Sub(*task.DueDate))
+		occurrence.StartDate = &start
+	}
+
+	_, err = s.repo.CreateOccurrence(ctx, occurrence)
+	return err
+}
+
+// checkParent ensures parentID is a task the caller can see and that it
+// belongs to ownerID, so a subtree never spans owners.
+func (s *taskService) checkParent(ctx context.Context, parentID, ownerID int64) error {
+	parent, err := s.repo.GetByID(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if parent == nil || parent.OwnerID != ownerID {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+func (s *taskService) checkReparent(ctx context.Context, task *models.Task, parentID int64) error {
+	if parentID == task.ID {
+		return ErrParentCycle
+	}
+	if err := s.checkParent(ctx, parentID, task.OwnerID); err != nil {
+		return err
+	}
+
+	cycle, err := s.repo.IsAncestor(ctx, task.ID, parentID)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return ErrParentCycle
+	}
+	return nil
+}
+
+func (s *taskService) checkNoOpenChildren(ctx context.Context, id int64) error {
+	children, err := s.repo.GetChildren(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if child.Status != models.StatusDone && child.Status != models.StatusCancelled {
+			return ErrOpenChildren
+		}
+	}
+	return nil
+}
+
+// checkCascade checks that the open descendants of id may be completed along
+// with it, and returns them: each must be allowed to move to done and get
+// past the blocker policy, with blockers in the same subtree counted as done.
+func (s *taskService) checkCascade(ctx context.Context, id int64) ([]*models.Task, error) {
+	tasks, err := s.repo.GetSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	finishing := make(map[int64]bool, len(tasks))
+	for _, task := range tasks {
+		finishing[task.ID] = true
+	}
+	var open []*models.Task
+	for _, task := range tasks {
+		if task.ID == id || task.Status == models.StatusDone || task.Status == models.StatusCancelled {
+			continue
+		}
+		if err := checkTransition(task.Status, models.StatusDone); err != nil {
+			return nil, fmt.Errorf("subtask %d: %w", task.ID, err)
+		}
+		if err := s.checkBlockers(ctx, task.ID, finishing); err != nil {
+			return nil, fmt.Errorf("subtask %d: %w", task.ID, err)
+		}
+		open = append(open, task)
+	}
+	return open, nil
+}
+
+// checkBlockers applies the blocker policy to a task that is about to be
+// started or completed. Blockers in finishing are being completed along with
+// it and count as done.
+func (s *taskService) checkBlockers(ctx context.Context, id int64, finishing map[int64]bool) error {
+	if s.deps == nil {
+		return nil
+	}
+
+	blockers, err := s.deps.GetBlockers(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	open := 0
+	for _, blocker := range blockers {
+		if blocker.Status != models.StatusDone && blocker.Status != models.StatusCancelled && !finishing[blocker.ID] {
+			open++
+		}
+	}
+	if open == 0 {
+		return nil
+	}
+
+	if s.blockers == BlockersWarn {
+		log.Printf("Task %d is moving on with %d open blockers", id, open)
+		return nil
+	}
+	return ErrTaskBlocked
+}
+
+func checkTransition(from, to models.Status) error {
+	if !to.Valid() {
+		return ErrInvalidInput
+	}
+	if !from.CanTransitionTo(to) {
+		return ErrInvalidTransition
+	}
+	return nil
+}
+
+// maxDueDateAge is how far in the past a due date may be set. Anything older
+// is far more likely a mistyped year than a real deadline.
+const maxDueDateAge = 365 * 24 * time.Hour
+
+// checkTaskFields adds to errs what is wrong with the fields of task that
+// its request's validate tags cannot express. old is the task being
+// replaced, if any; a due date it already had is not checked for age again,
+// so tasks that fell far behind stay editable. Ages are relative to now.
+func checkTaskFields(errs *validation.Errors, task, old *models.Task, now time.Time) {
+	if !task.Priority.Valid() {
+		errs.Add("priority", validation.CodeInvalid, "is not a known priority")
+	}
+	if !task.Status.Valid() {
+		errs.Add("status", validation.CodeInvalid, "is not a known status")
+	}
+
+	if task.DueDate != nil {
+		unchanged := old != nil && old.DueDate != nil && old.DueDate.Equal(*task.DueDate)
+		if !unchanged && task.DueDate.Before(now.Add(-maxDueDateAge)) {
+			errs.Add("due_date", validation.CodeOutOfRange, "must not be more than a year in the past")
+		}
+	} else if task.Recurrence != nil {
+		errs.Add("due_date", validation.CodeRequired, "is required for a recurring task")
+	}
+
+	if task.StartDate != nil && task.DueDate != nil && task.StartDate.After(*task.DueDate) {
+		errs.Add("start_date", validation.CodeOutOfRange, "must not be after due_date")
+	}
+}
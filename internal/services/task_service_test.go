@@ -3,8 +3,11 @@ package services_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"task-manager/internal/models"
+	"task-manager/internal/repository"
 	"task-manager/internal/services"
+	"task-manager/internal/validation"
 	"testing"
 	"time"
 
@@ -29,7 +32,7 @@ func (m *MockTaskRepository) GetByID(ctx context.Context, id int64) (*models.Tas
 	return args.Get(0).(*models.Task), args.Error(1)
 }
 
-func (m *MockTaskRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+func (m *MockTaskRepository) GetAll(ctx context.Context, filter *models.TaskFilter, limit, offset int) ([]*models.Task, int, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
 		return nil, 0, args.Error(1)
@@ -37,18 +40,31 @@ func (m *MockTaskRepository) GetAll(ctx context.Context, limit, offset int) ([]*
 	return args.Get(0).([]*models.Task), 0, args.Error(1)
 }
 
+func (m *MockTaskRepository) GetPage(ctx context.Context, filter *models.TaskFilter, cursor *models.TaskCursor, limit int) (*models.TaskPage, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TaskPage), args.Error(1)
+}
+
+func (m *MockTaskRepository) Count(ctx context.Context, filter *models.TaskFilter) (int, error) {
+	args := m.Called(ctx, filter)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockTaskRepository) Update(ctx context.Context, task *models.Task) error {
 	args := m.Called(ctx, task)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) MarkComplete(ctx context.Context, id int64) error {
-	args := m.Called(ctx, id)
+func (m *MockTaskRepository) MarkComplete(ctx context.Context, id, version int64) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) Delete(ctx context.Context, id int64) error {
-	args := m.Called(ctx, id)
+func (m *MockTaskRepository) Delete(ctx context.Context, id, version int64) error {
+	args := m.Called(ctx, id, version)
 	return args.Error(0)
 }
 
@@ -60,6 +76,79 @@ func (m *MockTaskRepository) GetDueTasks(ctx context.Context, from, to time.Time
 	return args.Get(0).([]*models.Task), args.Error(1)
 }
 
+func (m *MockTaskRepository) GetChildren(ctx context.Context, parentID int64) ([]*models.Task, error) {
+	args := m.Called(ctx, parentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) GetSubtree(ctx context.Context, rootID int64) ([]*models.Task, error) {
+	args := m.Called(ctx, rootID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) IsAncestor(ctx context.Context, ancestorID, id int64) (bool, error) {
+	args := m.Called(ctx, ancestorID, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) DeleteTree(ctx context.Context, rootID, version int64) error {
+	args := m.Called(ctx, rootID, version)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) CompleteTree(ctx context.Context, rootID, version int64) error {
+	args := m.Called(ctx, rootID, version)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) UpdateTree(ctx context.Context, task *models.Task) error {
+	args := m.Called(ctx, task)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) Search(ctx context.Context, terms []string, limit, offset int) ([]*models.TaskSearchResult, int, error) {
+	args := m.Called(ctx, terms, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.TaskSearchResult), args.Int(1), args.Error(2)
+}
+
+func (m *MockTaskRepository) GetTrash(ctx context.Context, limit, offset int) ([]*models.Task, int, error) {
+	args := m.Called(ctx, limit, offset)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]*models.Task), args.Int(1), args.Error(2)
+}
+
+func (m *MockTaskRepository) Restore(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, []string, error) {
+	args := m.Called(ctx, deletedBefore)
+	keys, _ := args.Get(1).([]string)
+	return args.Get(0).(int64), keys, args.Error(2)
+}
+
+func (m *MockTaskRepository) CreateOccurrence(ctx context.Context, task *models.Task) (bool, error) {
+	args := m.Called(ctx, task)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) StopRecurrence(ctx context.Context, recurrenceID int64) error {
+	args := m.Called(ctx, recurrenceID)
+	return args.Error(0)
+}
+
 // -------------------- Tests --------------------
 
 func TestTaskServiceMethods(t *testing.T) {
@@ -67,7 +156,7 @@ func TestTaskServiceMethods(t *testing.T) {
 	service := services.NewTaskService(mockRepo)
 
 	now := time.Now()
-	task := &models.Task{ID: 1, Title: "Test", Description: "Desc", DueDate: now, IsCompleted: false}
+	task := &models.Task{ID: 1, Title: "Test", Description: "Desc", DueDate: &now, Priority: models.PriorityMedium, Status: models.StatusTodo}
 
 	t.Run("CreateTask", func(t *testing.T) {
 		req := &models.CreateTaskRequest{Title: "Test", Description: "Desc", DueDate: now}
@@ -78,6 +167,8 @@ func TestTaskServiceMethods(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, req.Title, createdTask.Title)
 		assert.Equal(t, req.Description, createdTask.Description)
+		assert.Equal(t, models.PriorityMedium, createdTask.Priority)
+		assert.Equal(t, models.StatusTodo, createdTask.Status)
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -104,6 +195,35 @@ func TestTaskServiceMethods(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("CreateTask field errors", func(t *testing.T) {
+		late := now.Add(time.Hour)
+		testCases := map[string]struct {
+			req   *models.CreateTaskRequest
+			field string
+			code  string
+		}{
+			"missing title":    {req: &models.CreateTaskRequest{DueDate: now}, field: "title", code: validation.CodeRequired},
+			"long title":       {req: &models.CreateTaskRequest{Title: strings.Repeat("é", 201), DueDate: now}, field: "title", code: validation.CodeTooLong},
+			"missing due date": {req: &models.CreateTaskRequest{Title: "T"}, field: "due_date", code: validation.CodeRequired},
+			"ancient due date": {req: &models.CreateTaskRequest{Title: "T", DueDate: now.AddDate(-2, 0, 0)}, field: "due_date", code: validation.CodeOutOfRange},
+			"unknown status":   {req: &models.CreateTaskRequest{Title: "T", DueDate: now, Status: "later"}, field: "status", code: validation.CodeInvalid},
+			"start after due":  {req: &models.CreateTaskRequest{Title: "T", DueDate: now, StartDate: &late}, field: "start_date", code: validation.CodeOutOfRange},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.CreateTask(context.Background(), tc.req)
+				assert.ErrorIs(t, err, services.ErrInvalidInput)
+
+				var errs validation.Errors
+				if assert.ErrorAs(t, err, &errs) {
+					assert.Equal(t, tc.field, errs[0].Field)
+					assert.Equal(t, tc.code, errs[0].Code)
+				}
+			})
+		}
+	})
+
 	t.Run("GetTask", func(t *testing.T) {
 		testCases := map[string]struct {
 			id       int64
@@ -133,19 +253,42 @@ func TestTaskServiceMethods(t *testing.T) {
 
 	t.Run("GetAllTasks", func(t *testing.T) {
 		mockRepo.On("GetAll", mock.Anything).Return([]*models.Task{task}, nil).Once()
-		tasks, _, err := service.GetAllTasks(context.Background(), 1, 1)
+		tasks, _, err := service.GetAllTasks(context.Background(), nil, 1, 1)
 		assert.NoError(t, err)
 		assert.Len(t, tasks, 1)
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("GetAllTasks filter", func(t *testing.T) {
+		mockRepo.On("GetAll", mock.Anything).Return([]*models.Task{task}, nil).Once()
+		filter := &models.TaskFilter{Tags: []string{"work", " work", "", "home"}, Priorities: []models.Priority{models.PriorityHigh}}
+		_, _, err := service.GetAllTasks(context.Background(), filter, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"work", "home"}, filter.Tags)
+		assert.Equal(t, models.SortByCreatedAt, filter.SortBy)
+
+		later := time.Now()
+		earlier := later.Add(-time.Hour)
+		invalid := map[string]*models.TaskFilter{
+			"sort field":  {SortBy: "owner_id"},
+			"priority":    {Priorities: []models.Priority{"whenever"}},
+			"empty range": {DueFrom: &later, DueTo: &earlier},
+		}
+		for name, filter := range invalid {
+			t.Run(name, func(t *testing.T) {
+				_, _, err := service.GetAllTasks(context.Background(), filter, 10, 0)
+				assert.ErrorIs(t, err, services.ErrInvalidInput)
+			})
+		}
+	})
+
 	t.Run("UpdateTask", func(t *testing.T) {
-		req := &models.UpdateTaskRequest{Title: "Updated", Description: "Updated Desc", DueDate: now}
+		req := &models.UpdateTaskRequest{Title: "Updated", Description: "Updated Desc", DueDate: &now}
 
 		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
 		mockRepo.On("Update", mock.Anything, task).Return(nil).Once()
 
-		updatedTask, err := service.UpdateTask(context.Background(), 1, req)
+		updatedTask, err := service.UpdateTask(context.Background(), 1, req, 0)
 		assert.NoError(t, err)
 		assert.Equal(t, "Updated", updatedTask.Title)
 		assert.Equal(t, "Updated Desc", updatedTask.Description)
@@ -153,18 +296,111 @@ func TestTaskServiceMethods(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("UpdateTask lost ownership", func(t *testing.T) {
+		req := &models.UpdateTaskRequest{Title: "Updated"}
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("Update", mock.Anything, task).Return(repository.ErrTaskNotFound).Once()
+
+		got, err := service.UpdateTask(context.Background(), 1, req, 0)
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+		assert.Nil(t, got)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTask keeps an overlong title", func(t *testing.T) {
+		long := strings.Repeat("t", 250)
+		old := &models.Task{ID: 9, Title: long, Priority: models.PriorityMedium, Status: models.StatusTodo}
+
+		mockRepo.On("GetByID", mock.Anything, int64(9)).Return(old, nil).Once()
+		mockRepo.On("Update", mock.Anything, old).Return(nil).Once()
+		_, err := service.UpdateTask(context.Background(), 9, &models.UpdateTaskRequest{Title: long, Description: "now with notes"}, 0)
+		assert.NoError(t, err)
+
+		old = &models.Task{ID: 9, Title: long, Priority: models.PriorityMedium, Status: models.StatusTodo}
+		mockRepo.On("GetByID", mock.Anything, int64(9)).Return(old, nil).Once()
+		_, err = service.UpdateTask(context.Background(), 9, &models.UpdateTaskRequest{Title: long + "!"}, 0)
+		var errs validation.Errors
+		assert.ErrorAs(t, err, &errs)
+		assert.True(t, errs.Has("title"))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateTask invalid priority", func(t *testing.T) {
+		req := &models.CreateTaskRequest{Title: "Test", DueDate: now, Priority: "critical"}
+
+		got, err := service.CreateTask(context.Background(), req)
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+		assert.Nil(t, got)
+	})
+
+	t.Run("CreateTask start after due", func(t *testing.T) {
+		start := now.Add(time.Hour)
+		req := &models.CreateTaskRequest{Title: "Test", DueDate: now, StartDate: &start}
+
+		got, err := service.CreateTask(context.Background(), req)
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+		assert.Nil(t, got)
+	})
+
+	t.Run("UpdateTask status transition", func(t *testing.T) {
+		testCases := map[string]struct {
+			from    models.Status
+			to      models.Status
+			wantErr error
+		}{
+			"todo to in_progress":   {from: models.StatusTodo, to: models.StatusInProgress},
+			"blocked to done":       {from: models.StatusBlocked, to: models.StatusDone, wantErr: services.ErrInvalidTransition},
+			"cancelled to todo":     {from: models.StatusCancelled, to: models.StatusTodo},
+			"cancelled to progress": {from: models.StatusCancelled, to: models.StatusInProgress, wantErr: services.ErrInvalidTransition},
+			"unknown status":        {from: models.StatusTodo, to: "archived", wantErr: services.ErrInvalidInput},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				existing := &models.Task{ID: 2, Title: "T", DueDate: &now, Status: tc.from}
+				mockRepo.On("GetByID", mock.Anything, int64(2)).Return(existing, nil).Once()
+				if tc.wantErr == nil {
+					mockRepo.On("Update", mock.Anything, existing).Return(nil).Once()
+				}
+
+				got, err := service.UpdateTask(context.Background(), 2, &models.UpdateTaskRequest{Title: "T", Status: tc.to}, 0)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					assert.Nil(t, got)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, tc.to, got.Status)
+				}
+				mockRepo.AssertExpectations(t)
+			})
+		}
+	})
+
 	t.Run("MarkTaskComplete", func(t *testing.T) {
-		mockRepo.On("MarkComplete", mock.Anything, int64(1)).Return(nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("MarkComplete", mock.Anything, int64(1), int64(0)).Return(nil).Once()
 
-		err := service.MarkTaskComplete(context.Background(), 1)
+		err := service.MarkTaskComplete(context.Background(), 1, 0)
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("MarkTaskComplete cancelled", func(t *testing.T) {
+		cancelled := &models.Task{ID: 3, Status: models.StatusCancelled}
+		mockRepo.On("GetByID", mock.Anything, int64(3)).Return(cancelled, nil).Once()
+
+		err := service.MarkTaskComplete(context.Background(), 3, 0)
+		assert.ErrorIs(t, err, services.ErrInvalidTransition)
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("DeleteTask", func(t *testing.T) {
-		mockRepo.On("Delete", mock.Anything, int64(1)).Return(nil).Once()
+		mockRepo.On("Delete", mock.Anything, int64(1), int64(0)).Return(nil).Once()
 
-		err := service.DeleteTask(context.Background(), 1)
+		err := service.DeleteTask(context.Background(), 1, 0)
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
@@ -182,3 +418,662 @@ func TestTaskServiceMethods(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestTaskHierarchy(t *testing.T) {
+	ptr := func(id int64) *int64 { return &id }
+
+	t.Run("UpdateTask parent cycle", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		root := &models.Task{ID: 1, OwnerID: 7, Status: models.StatusTodo}
+		grandchild := &models.Task{ID: 3, OwnerID: 7, ParentID: ptr(2), Status: models.StatusTodo}
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(root, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(3)).Return(grandchild, nil).Once()
+		mockRepo.On("IsAncestor", mock.Anything, int64(1), int64(3)).Return(true, nil).Once()
+
+		_, err := service.UpdateTask(context.Background(), 1, &models.UpdateTaskRequest{Title: "T", ParentID: ptr(3)}, 0)
+		assert.ErrorIs(t, err, services.ErrParentCycle)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTask own parent", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusTodo}, nil).Once()
+
+		_, err := service.UpdateTask(context.Background(), 1, &models.UpdateTaskRequest{Title: "T", ParentID: ptr(1)}, 0)
+		assert.ErrorIs(t, err, services.ErrParentCycle)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTask parent of another owner", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, OwnerID: 7, Status: models.StatusTodo}, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.Task{ID: 2, OwnerID: 8}, nil).Once()
+
+		_, err := service.UpdateTask(context.Background(), 1, &models.UpdateTaskRequest{Title: "T", ParentID: ptr(2)}, 0)
+		assert.ErrorIs(t, err, services.ErrInvalidParent)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("DeleteTask restrict", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteRestrict,
+			OnComplete: services.CompleteIgnore,
+		}))
+
+		mockRepo.On("GetChildren", mock.Anything, int64(1)).Return([]*models.Task{{ID: 2}}, nil).Once()
+
+		err := service.DeleteTask(context.Background(), 1, 0)
+		assert.ErrorIs(t, err, services.ErrHasChildren)
+		mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("DeleteTask cascade", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteCascade,
+			OnComplete: services.CompleteIgnore,
+		}))
+
+		mockRepo.On("DeleteTree", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+
+		assert.NoError(t, service.DeleteTask(context.Background(), 1, 0))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("MarkTaskComplete restrict", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteOrphan,
+			OnComplete: services.CompleteRestrict,
+		}))
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusInProgress}, nil).Once()
+		mockRepo.On("GetChildren", mock.Anything, int64(1)).Return([]*models.Task{
+			{ID: 2, Status: models.StatusDone},
+			{ID: 3, Status: models.StatusTodo},
+		}, nil).Once()
+
+		err := service.MarkTaskComplete(context.Background(), 1, 0)
+		assert.ErrorIs(t, err, services.ErrOpenChildren)
+		mockRepo.AssertNotCalled(t, "MarkComplete", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("MarkTaskComplete cascade", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteOrphan,
+			OnComplete: services.CompleteCascade,
+		}))
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusInProgress}, nil).Once()
+		mockRepo.On("GetSubtree", mock.Anything, int64(1)).Return([]*models.Task{
+			{ID: 1, Status: models.StatusInProgress},
+			{ID: 2, ParentID: ptr(1), Status: models.StatusTodo},
+			{ID: 3, ParentID: ptr(1), Status: models.StatusCancelled},
+		}, nil).Once()
+		mockRepo.On("CompleteTree", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+
+		assert.NoError(t, service.MarkTaskComplete(context.Background(), 1, 0))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("MarkTaskComplete cascade checks subtasks", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewTaskService(mockRepo,
+			services.WithHierarchyPolicy(services.HierarchyPolicy{OnDelete: services.DeleteOrphan, OnComplete: services.CompleteCascade}),
+			services.WithDependencies(mockDeps, services.BlockersRefuse),
+		)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusInProgress}, nil)
+		mockDeps.On("GetBlockers", mock.Anything, int64(1)).Return([]*models.Task{}, nil)
+
+		// A blocked subtask cannot go straight to done.
+		mockRepo.On("GetSubtree", mock.Anything, int64(1)).Return([]*models.Task{
+			{ID: 1, Status: models.StatusInProgress},
+			{ID: 2, ParentID: ptr(1), Status: models.StatusBlocked},
+		}, nil).Once()
+		assert.ErrorIs(t, service.MarkTaskComplete(context.Background(), 1, 0), services.ErrInvalidTransition)
+
+		// A subtask waiting on a sibling is fine; one waiting on an open task
+		// outside the subtree is not.
+		subtree := []*models.Task{
+			{ID: 1, Status: models.StatusInProgress},
+			{ID: 2, ParentID: ptr(1), Status: models.StatusTodo},
+			{ID: 3, ParentID: ptr(1), Status: models.StatusTodo},
+		}
+		mockRepo.On("GetSubtree", mock.Anything, int64(1)).Return(subtree, nil).Twice()
+		mockDeps.On("GetBlockers", mock.Anything, int64(2)).Return([]*models.Task{{ID: 3, Status: models.StatusTodo}}, nil).Once()
+		mockDeps.On("GetBlockers", mock.Anything, int64(3)).Return([]*models.Task{}, nil).Once()
+		mockRepo.On("CompleteTree", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+		assert.NoError(t, service.MarkTaskComplete(context.Background(), 1, 0))
+
+		mockDeps.On("GetBlockers", mock.Anything, int64(2)).Return([]*models.Task{{ID: 9, Status: models.StatusTodo}}, nil).Once()
+		assert.ErrorIs(t, service.MarkTaskComplete(context.Background(), 1, 0), services.ErrTaskBlocked)
+
+		mockRepo.AssertNumberOfCalls(t, "CompleteTree", 1)
+	})
+
+	t.Run("UpdateTask cascade completes in one write", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteOrphan,
+			OnComplete: services.CompleteCascade,
+		}))
+
+		task := &models.Task{ID: 1, Title: "T", Priority: models.PriorityMedium, Status: models.StatusInProgress}
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("GetSubtree", mock.Anything, int64(1)).Return([]*models.Task{
+			{ID: 1, Status: models.StatusInProgress},
+			{ID: 2, ParentID: ptr(1), Status: models.StatusTodo},
+		}, nil).Once()
+		mockRepo.On("UpdateTree", mock.Anything, task).Return(nil).Once()
+
+		_, err := service.UpdateTask(context.Background(), 1, &models.UpdateTaskRequest{Title: "T", Status: models.StatusDone}, 0)
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("GetSubtree", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetSubtree", mock.Anything, int64(1)).Return([]*models.Task{
+			{ID: 1},
+			{ID: 2, ParentID: ptr(1)},
+			{ID: 3, ParentID: ptr(2)},
+			{ID: 4, ParentID: ptr(1)},
+		}, nil).Once()
+
+		root, err := service.GetSubtree(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, root.Children, 2)
+		assert.Equal(t, int64(3), root.Children[0].Children[0].ID)
+		assert.Empty(t, root.Children[1].Children)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("GetSubtree not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetSubtree", mock.Anything, int64(9)).Return([]*models.Task{}, nil).Once()
+
+		_, err := service.GetSubtree(context.Background(), 9)
+		assert.ErrorIs(t, err, services.ErrTaskNotFound)
+	})
+}
+
+func TestTaskBlockers(t *testing.T) {
+	ctx := context.Background()
+	openBlocker := []*models.Task{{ID: 2, Status: models.StatusInProgress}, {ID: 3, Status: models.StatusDone}}
+
+	t.Run("MarkTaskComplete refused", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewTaskService(mockRepo, services.WithDependencies(mockDeps, services.BlockersRefuse))
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusInProgress}, nil).Once()
+		mockDeps.On("GetBlockers", mock.Anything, int64(1)).Return(openBlocker, nil).Once()
+
+		err := service.MarkTaskComplete(ctx, 1, 0)
+		assert.ErrorIs(t, err, services.ErrTaskBlocked)
+		mockRepo.AssertNotCalled(t, "MarkComplete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("MarkTaskComplete warns", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewTaskService(mockRepo, services.WithDependencies(mockDeps, services.BlockersWarn))
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusInProgress}, nil).Once()
+		mockDeps.On("GetBlockers", mock.Anything, int64(1)).Return(openBlocker, nil).Once()
+		mockRepo.On("MarkComplete", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+
+		assert.NoError(t, service.MarkTaskComplete(ctx, 1, 0))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateTask start while blocked", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockDeps := new(MockDependencyRepository)
+		service := services.NewTaskService(mockRepo, services.WithDependencies(mockDeps, services.BlockersRefuse))
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(&models.Task{ID: 1, Status: models.StatusTodo}, nil).Once()
+		mockDeps.On("GetBlockers", mock.Anything, int64(1)).Return(openBlocker, nil).Once()
+
+		_, err := service.UpdateTask(ctx, 1, &models.UpdateTaskRequest{Title: "T", Status: models.StatusInProgress}, 0)
+		assert.ErrorIs(t, err, services.ErrTaskBlocked)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+}
+
+func TestTaskRecurrence(t *testing.T) {
+	ctx := context.Background()
+	// 2025-01-06 is a Monday.
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	clock := services.WithClock(func() time.Time { return start })
+	series := func() *models.Task {
+		lead := start.Add(-2 * time.Hour)
+		return &models.Task{
+			ID: 1, Title: "Bins", DueDate: &start, StartDate: &lead, OwnerID: 7,
+			Priority: models.PriorityLow, Status: models.StatusTodo,
+			Tags:       []*models.Tag{{ID: 4}},
+			Recurrence: &models.Recurrence{ID: 3, Rule: "FREQ=WEEKLY;BYDAY=MO,TH", StartsAt: start, Occurrence: 1},
+		}
+	}
+
+	t.Run("CreateTask with rule", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock)
+
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Task")).Return(nil).Once()
+
+		task, err := service.CreateTask(ctx, &models.CreateTaskRequest{Title: "Bins", DueDate: start, Recurrence: "rrule:freq=weekly;byday=th,mo"})
+		assert.NoError(t, err)
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TH", task.Recurrence.Rule)
+		assert.Equal(t, start, task.Recurrence.StartsAt)
+	})
+
+	t.Run("CreateTask with bad rule", func(t *testing.T) {
+		service := services.NewTaskService(new(MockTaskRepository), clock)
+
+		_, err := service.CreateTask(ctx, &models.CreateTaskRequest{Title: "Bins", DueDate: start, Recurrence: "FREQ=HOURLY"})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+
+		_, err = service.CreateTask(ctx, &models.CreateTaskRequest{Title: "Bins", Recurrence: "FREQ=DAILY"})
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+
+	t.Run("MarkTaskComplete spawns next occurrence", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(series(), nil).Once()
+		mockRepo.On("MarkComplete", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+		mockRepo.On("CreateOccurrence", mock.Anything, mock.MatchedBy(func(next *models.Task) bool {
+			thursday := time.Date(2025, 1, 9, 9, 0, 0, 0, time.UTC)
+			return next.DueDate.Equal(thursday) &&
+				next.StartDate.Equal(thursday.Add(-2*time.Hour)) &&
+				next.Status == models.StatusTodo &&
+				next.OwnerID == 7 &&
+				len(next.Tags) == 1 &&
+				next.Recurrence.ID == 3 && next.Recurrence.Occurrence == 2
+		})).Return(true, nil).Once()
+
+		assert.NoError(t, service.MarkTaskComplete(ctx, 1, 0))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("MarkTaskComplete cascade spawns for subtasks", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock, services.WithHierarchyPolicy(services.HierarchyPolicy{
+			OnDelete:   services.DeleteOrphan,
+			OnComplete: services.CompleteCascade,
+		}))
+
+		parentID := int64(10)
+		child := series()
+		child.ID, child.ParentID = 2, &parentID
+		mockRepo.On("GetByID", mock.Anything, int64(10)).Return(&models.Task{ID: 10, Status: models.StatusInProgress}, nil).Once()
+		mockRepo.On("GetSubtree", mock.Anything, int64(10)).Return([]*models.Task{{ID: 10, Status: models.StatusInProgress}, child}, nil).Once()
+		mockRepo.On("CompleteTree", mock.Anything, int64(10), int64(0)).Return(nil).Once()
+		mockRepo.On("CreateOccurrence", mock.Anything, mock.MatchedBy(func(next *models.Task) bool {
+			return next.Recurrence.ID == 3 && next.Recurrence.Occurrence == 2 && *next.ParentID == 10
+		})).Return(true, nil).Once()
+
+		assert.NoError(t, service.MarkTaskComplete(ctx, 10, 0))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("MarkTaskComplete stopped series", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock)
+
+		task := series()
+		stopped := start
+		task.Recurrence.StoppedAt = &stopped
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(task, nil).Once()
+		mockRepo.On("MarkComplete", mock.Anything, int64(1), int64(0)).Return(nil).Once()
+
+		assert.NoError(t, service.MarkTaskComplete(ctx, 1, 0))
+		mockRepo.AssertNotCalled(t, "CreateOccurrence", mock.Anything, mock.Anything)
+	})
+
+	t.Run("PreviewOccurrences", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(series(), nil).Once()
+
+		resp, err := service.PreviewOccurrences(ctx, 1, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{
+			time.Date(2025, 1, 9, 9, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC),
+		}, resp.Occurrences)
+	})
+
+	t.Run("StopRecurrence", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo, clock)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(series(), nil).Once()
+		mockRepo.On("StopRecurrence", mock.Anything, int64(3)).Return(nil).Once()
+		mockRepo.On("GetByID", mock.Anything, int64(2)).Return(&models.Task{ID: 2}, nil).Once()
+
+		assert.NoError(t, service.StopRecurrence(ctx, 1))
+		assert.ErrorIs(t, service.StopRecurrence(ctx, 2), services.ErrNotRecurring)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTaskTrash(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetTrash", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		deletedAt := time.Now()
+		trashed := []*models.Task{{ID: 1, Title: "Old", DeletedAt: &deletedAt}}
+		mockRepo.On("GetTrash", mock.Anything, 10, 0).Return(trashed, 1, nil).Once()
+
+		tasks, total, err := service.GetTrash(ctx, 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, trashed, tasks)
+	})
+
+	t.Run("RestoreTask", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("Restore", mock.Anything, int64(1)).Return(nil).Once()
+		mockRepo.On("Restore", mock.Anything, int64(2)).Return(repository.ErrTaskNotFound).Once()
+
+		assert.NoError(t, service.RestoreTask(ctx, 1))
+		assert.ErrorIs(t, service.RestoreTask(ctx, 2), services.ErrTaskNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTaskSearch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("SearchTasks splits terms", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		results := []*models.TaskSearchResult{{Task: &models.Task{ID: 1}, Rank: 0.6, TitleHighlight: "<mark>Budget</mark> review"}}
+		mockRepo.On("Search", mock.Anything, []string{"budget", "q3", "café"}, 10, 0).Return(results, 1, nil).Once()
+
+		got, total, err := service.SearchTasks(ctx, "  Budget: Q3 & café!", 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, results, got)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("SearchTasks invalid query", func(t *testing.T) {
+		service := services.NewTaskService(new(MockTaskRepository))
+
+		queries := map[string]string{
+			"empty":          "",
+			"only operators": "& | !:*",
+			"too many terms": "a b c d e f g h i j k",
+			"too long":       strings.Repeat("x", 201),
+		}
+		for name, query := range queries {
+			t.Run(name, func(t *testing.T) {
+				_, _, err := service.SearchTasks(ctx, query, 10, 0)
+				assert.ErrorIs(t, err, services.ErrInvalidInput)
+			})
+		}
+	})
+}
+
+func TestTaskListCursor(t *testing.T) {
+	ctx := context.Background()
+	created := time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC)
+
+	t.Run("ListTasks round trip", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		filter := &models.TaskFilter{Descending: true}
+		next := &models.TaskCursor{SortBy: models.SortByCreatedAt, Descending: true, Key: created, ID: 42}
+		mockRepo.On("GetPage", mock.Anything, filter, (*models.TaskCursor)(nil), 2).
+			Return(&models.TaskPage{Tasks: []*models.Task{{ID: 43}, {ID: 42}}, Next: next}, nil).Once()
+
+		first, err := service.ListTasks(ctx, filter, "", 2, false)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, first.NextCursor)
+		assert.Empty(t, first.PrevCursor)
+		assert.Nil(t, first.Total)
+
+		mockRepo.On("GetPage", mock.Anything, filter, mock.MatchedBy(func(c *models.TaskCursor) bool {
+			return c.ID == 42 && c.Key == created.Format(time.RFC3339Nano) && !c.Before
+		}), 2).Return(&models.TaskPage{Tasks: []*models.Task{{ID: 41}}}, nil).Once()
+		mockRepo.On("Count", mock.Anything, filter).Return(3, nil).Once()
+
+		second, err := service.ListTasks(ctx, filter, first.NextCursor, 2, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, *second.Total)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ListTasks invalid cursor", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		next := &models.TaskCursor{SortBy: models.SortByCreatedAt, Descending: true, Key: created, ID: 42}
+		mockRepo.On("GetPage", mock.Anything, mock.Anything, (*models.TaskCursor)(nil), 1).
+			Return(&models.TaskPage{Tasks: []*models.Task{{ID: 42}}, Next: next}, nil).Once()
+		page, err := service.ListTasks(ctx, &models.TaskFilter{Descending: true}, "", 1, false)
+		assert.NoError(t, err)
+
+		cases := map[string]struct {
+			filter *models.TaskFilter
+			cursor string
+		}{
+			"not base64":       {&models.TaskFilter{Descending: true}, "%%%"},
+			"not json":         {&models.TaskFilter{Descending: true}, "bm90IGpzb24"},
+			"other sort field": {&models.TaskFilter{SortBy: models.SortByDueDate, Descending: true}, page.NextCursor},
+			"other direction":  {&models.TaskFilter{}, page.NextCursor},
+		}
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				_, err := service.ListTasks(ctx, tc.filter, tc.cursor, 1, false)
+				assert.ErrorIs(t, err, services.ErrInvalidCursor)
+			})
+		}
+	})
+}
+
+func TestTaskVersions(t *testing.T) {
+	ctx := context.Background()
+	current := func() *models.Task {
+		return &models.Task{ID: 1, Title: "Draft", Status: models.StatusTodo, Priority: models.PriorityLow, Version: 5}
+	}
+
+	t.Run("UpdateTask writes against the version it read", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(task *models.Task) bool {
+			return task.Version == 5 && task.Title == "Final"
+		})).Return(nil).Once()
+
+		_, err := service.UpdateTask(ctx, 1, &models.UpdateTaskRequest{Title: "Final"}, 5)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil)
+
+		_, err := service.UpdateTask(ctx, 1, &models.UpdateTaskRequest{Title: "Final"}, 4)
+		assert.ErrorIs(t, err, services.ErrVersionMismatch)
+		assert.ErrorIs(t, service.MarkTaskComplete(ctx, 1, 4), services.ErrVersionMismatch)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "MarkComplete", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent change caught by the repository", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+		mockRepo.On("MarkComplete", mock.Anything, int64(1), int64(5)).Return(repository.ErrVersionMismatch).Once()
+
+		assert.ErrorIs(t, service.MarkTaskComplete(ctx, 1, 0), services.ErrVersionMismatch)
+	})
+
+	t.Run("DeleteTask passes version", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("Delete", mock.Anything, int64(1), int64(5)).Return(nil).Once()
+
+		assert.NoError(t, service.DeleteTask(ctx, 1, 5))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTaskPatch(t *testing.T) {
+	ctx := context.Background()
+	due := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	parent := int64(4)
+	current := func() *models.Task {
+		return &models.Task{
+			ID: 1, Title: "Draft", Description: "Notes", DueDate: &due, OwnerID: 7, ParentID: &parent,
+			Priority: models.PriorityHigh, Status: models.StatusInProgress, Version: 3,
+		}
+	}
+
+	t.Run("PUT clears omitted fields", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+		mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Task")).Return(nil).Once()
+
+		got, err := service.UpdateTask(ctx, 1, &models.UpdateTaskRequest{Title: "Final"}, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "Final", got.Title)
+		assert.Empty(t, got.Description)
+		assert.Nil(t, got.DueDate)
+		assert.Nil(t, got.ParentID)
+		assert.Equal(t, models.PriorityMedium, got.Priority)
+		assert.Equal(t, models.StatusTodo, got.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("PUT needs a title", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+
+		_, err := service.UpdateTask(ctx, 1, &models.UpdateTaskRequest{Description: "x"}, 0)
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("merge patch keeps other fields", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(task *models.Task) bool {
+			return task.Version == 3
+		})).Return(nil).Once()
+
+		got, err := service.PatchTask(ctx, 1, []byte(`{"title":"Final","due_date":null}`), models.MergePatch, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, "Final", got.Title)
+		assert.Equal(t, "Notes", got.Description)
+		assert.Nil(t, got.DueDate)
+		assert.Equal(t, &parent, got.ParentID)
+		assert.Equal(t, models.PriorityHigh, got.Priority)
+		assert.Equal(t, models.StatusInProgress, got.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+		mockRepo.On("Update", mock.Anything, mock.AnythingOfType("*models.Task")).Return(nil).Once()
+
+		patch := `[
+			{"op":"test","path":"/title","value":"Draft"},
+			{"op":"replace","path":"/priority","value":"urgent"},
+			{"op":"copy","from":"/title","path":"/description"},
+			{"op":"remove","path":"/parent_id"}
+		]`
+		got, err := service.PatchTask(ctx, 1, []byte(patch), models.JSONPatch, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriorityUrgent, got.Priority)
+		assert.Equal(t, "Draft", got.Description)
+		assert.Nil(t, got.ParentID)
+		assert.True(t, due.Equal(*got.DueDate))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejected patches", func(t *testing.T) {
+		testCases := map[string]struct {
+			format  models.PatchFormat
+			patch   string
+			wantErr error
+		}{
+			"failed test":      {models.JSONPatch, `[{"op":"test","path":"/title","value":"Other"}]`, services.ErrPatchTestFailed},
+			"malformed":        {models.JSONPatch, `[{"op":"replace"`, services.ErrInvalidPatch},
+			"missing member":   {models.JSONPatch, `[{"op":"remove","path":"/recurrence"}]`, services.ErrInvalidPatch},
+			"read-only field":  {models.MergePatch, `{"owner_id":8}`, services.ErrInvalidPatch},
+			"wrong type":       {models.MergePatch, `{"priority":3}`, services.ErrInvalidPatch},
+			"cleared title":    {models.MergePatch, `{"title":null}`, services.ErrInvalidInput},
+			"unsupported type": {"application/json", `{}`, services.ErrInvalidPatch},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockTaskRepository)
+				service := services.NewTaskService(mockRepo)
+				mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+
+				_, err := service.PatchTask(ctx, 1, []byte(tc.patch), tc.format, 0)
+				assert.ErrorIs(t, err, tc.wantErr)
+				mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := services.NewTaskService(mockRepo)
+
+		mockRepo.On("GetByID", mock.Anything, int64(1)).Return(current(), nil).Once()
+
+		_, err := service.PatchTask(ctx, 1, []byte(`{"title":"x"}`), models.MergePatch, 2)
+		assert.ErrorIs(t, err, services.ErrVersionMismatch)
+	})
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type TokenService interface {
+	IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	Logout(ctx context.Context, principal *auth.Principal, refreshToken string) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type tokenService struct {
+	repo  repository.TokenRepository
+	users repository.UserRepository
+	keys  *auth.KeySet
+}
+
+func NewTokenService(repo repository.TokenRepository, users repository.UserRepository, keys *auth.KeySet) TokenService {
+	return &tokenService{repo: repo, users: users, keys: keys}
+}
+
+func (s *tokenService) IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	refresh, rawRefresh, err := newRefreshToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRefreshToken(ctx, refresh); err != nil {
+		return nil, err
+	}
+
+	return s.tokenPair(user, rawRefresh)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token is single-use: presenting one that was already
+// rotated is treated as theft and revokes every refresh token of its user.
+func (s *tokenService) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	current, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current == nil || time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	if current.RevokedAt != nil {
+		if err := s.repo.RevokeUserRefreshTokens(ctx, current.UserID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.users.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidToken
+	}
+
+	next, rawNext, err := newRefreshToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.RotateRefreshToken(ctx, current.ID, next)
+	if err != nil {
+		if errors.Is(err, repository.ErrTokenRevoked) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	return s.tokenPair(user, rawNext)
+}
+
+// Logout revokes the access token the caller authenticated with and, when
+// given, the refresh token issued alongside it.
+func (s *tokenService) Logout(ctx context.Context, principal *auth.Principal, refreshToken string) error {
+	if principal.TokenID != "" {
+		if err := s.repo.RevokeAccessToken(ctx, principal.TokenID, principal.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	current, err := s.repo.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if current == nil || current.UserID != principal.UserID {
+		return ErrInvalidToken
+	}
+
+	return s.repo.RevokeRefreshToken(ctx, current.TokenHash)
+}
+
+func (s *tokenService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	return s.repo.IsAccessTokenRevoked(ctx, jti)
+}
+
+func (s *tokenService) tokenPair(user *models.User, rawRefresh string) (*models.TokenPair, error) {
+	access, err := s.signAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: rawRefresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// signAccessToken signs a JWT for user. The subject is the user's ID, which
+// stays stable across username changes, and jti identifies the token for
+// revocation.
+func (s *tokenService) signAccessToken(user *models.User) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return s.keys.Sign(jwt.MapClaims{
+		"sub":      strconv.FormatInt(user.ID, 10),
+		"username": user.Username,
+		"role":     string(user.Role),
+		"jti":      jti,
+		"iat":      now.Unix(),
+		"exp":      now.Add(AccessTokenTTL).Unix(),
+	})
+}
+
+// newRefreshToken returns the record to store and the raw token to hand to the
+// client. Only the SHA-256 of the token is persisted.
+func newRefreshToken(userID int64) (*models.RefreshToken, string, error) {
+	raw, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}, raw, nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,156 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"task-manager/internal/auth"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RefreshToken), args.Error(1)
+}
+
+func (m *MockTokenRepository) RotateRefreshToken(ctx context.Context, oldID int64, next *models.RefreshToken) error {
+	args := m.Called(ctx, oldID, next)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID int64) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(ctx, jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestTokenServiceMethods(t *testing.T) {
+	mockRepo := new(MockTokenRepository)
+	mockUsers := new(MockUserRepository)
+	key, err := auth.GenerateEd25519Key("test")
+	assert.NoError(t, err)
+	keys, err := auth.NewKeySet(key.ID, key)
+	assert.NoError(t, err)
+	service := services.NewTokenService(mockRepo, mockUsers, keys)
+
+	user := &models.User{ID: 7, Username: "alice"}
+
+	t.Run("IssueTokens", func(t *testing.T) {
+		var stored *models.RefreshToken
+		mockRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.RefreshToken) }).
+			Return(nil).Once()
+
+		pair, err := service.IssueTokens(context.Background(), user)
+		assert.NoError(t, err)
+		assert.Equal(t, sha256Hex(pair.RefreshToken), stored.TokenHash)
+		assert.Equal(t, int64(services.AccessTokenTTL.Seconds()), pair.ExpiresIn)
+
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(pair.AccessToken, claims, keys.Keyfunc)
+		assert.NoError(t, err)
+		assert.Equal(t, "test", token.Header["kid"])
+		assert.Equal(t, "7", claims["sub"])
+		assert.NotEmpty(t, claims["jti"])
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Refresh rotates token", func(t *testing.T) {
+		current := &models.RefreshToken{ID: 3, UserID: 7, TokenHash: sha256Hex("old"), ExpiresAt: time.Now().Add(time.Hour)}
+		mockRepo.On("GetRefreshToken", mock.Anything, sha256Hex("old")).Return(current, nil).Once()
+		mockUsers.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+		mockRepo.On("RotateRefreshToken", mock.Anything, int64(3), mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+
+		pair, err := service.Refresh(context.Background(), "old")
+		assert.NoError(t, err)
+		assert.NotEqual(t, "old", pair.RefreshToken)
+
+		mockRepo.AssertExpectations(t)
+		mockUsers.AssertExpectations(t)
+	})
+
+	t.Run("Refresh reuse revokes all", func(t *testing.T) {
+		revokedAt := time.Now().Add(-time.Minute)
+		current := &models.RefreshToken{ID: 3, UserID: 7, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+		mockRepo.On("GetRefreshToken", mock.Anything, sha256Hex("reused")).Return(current, nil).Once()
+		mockRepo.On("RevokeUserRefreshTokens", mock.Anything, int64(7)).Return(nil).Once()
+
+		pair, err := service.Refresh(context.Background(), "reused")
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+		assert.Nil(t, pair)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Refresh concurrent rotation", func(t *testing.T) {
+		current := &models.RefreshToken{ID: 4, UserID: 7, ExpiresAt: time.Now().Add(time.Hour)}
+		mockRepo.On("GetRefreshToken", mock.Anything, sha256Hex("raced")).Return(current, nil).Once()
+		mockUsers.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+		mockRepo.On("RotateRefreshToken", mock.Anything, int64(4), mock.Anything).Return(repository.ErrTokenRevoked).Once()
+
+		_, err := service.Refresh(context.Background(), "raced")
+		assert.ErrorIs(t, err, services.ErrInvalidToken)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		exp := time.Now().Add(time.Minute)
+		principal := &auth.Principal{UserID: 7, TokenID: "jti-1", ExpiresAt: exp}
+		current := &models.RefreshToken{ID: 5, UserID: 7, TokenHash: sha256Hex("mine")}
+
+		mockRepo.On("RevokeAccessToken", mock.Anything, "jti-1", exp).Return(nil).Once()
+		mockRepo.On("GetRefreshToken", mock.Anything, sha256Hex("mine")).Return(current, nil).Once()
+		mockRepo.On("RevokeRefreshToken", mock.Anything, sha256Hex("mine")).Return(nil).Once()
+
+		err := service.Logout(context.Background(), principal, "mine")
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
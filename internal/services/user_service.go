@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	// ErrNoPassword is returned for accounts provisioned by single sign-on,
+	// which sign in through their identity provider and have no password to
+	// change.
+	ErrNoPassword = errors.New("account signs in with single sign-on and has no password")
+)
+
+const (
+	minPasswordLength = 8
+	maxUsernameLength = 50
+	// bcrypt ignores everything past 72 bytes, so longer passwords are rejected
+	// rather than silently truncated.
+	maxPasswordLength = 72
+)
+
+// dummyPasswordHash is a bcrypt hash at bcrypt.DefaultCost that no password
+// is expected to match. Authenticate checks passwords for unknown usernames
+// against it, so they take as long to reject as wrong passwords and do not
+// give away which accounts exist.
+const dummyPasswordHash = "$2a$10$dcrpI77l/CdI6QZWWkQvnOdZdQOvqQ4jVcVrm8b2MUKC2RnXaXqdS"
+
+type UserService interface {
+	Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error)
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+	ChangePassword(ctx context.Context, userID int64, req *models.ChangePasswordRequest) error
+	UpdateRole(ctx context.Context, userID int64, role models.Role) error
+}
+
+type userService struct {
+	repo   repository.UserRepository
+	tokens repository.TokenRepository
+}
+
+func NewUserService(repo repository.UserRepository, tokens repository.TokenRepository) UserService {
+	return &userService{repo: repo, tokens: tokens}
+}
+
+func (s *userService) Register(ctx context.Context, req *models.RegisterRequest) (*models.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if username == "" || len(username) > maxUsernameLength {
+		return nil, ErrInvalidInput
+	}
+	if err := validatePassword(req.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         models.RoleMember,
+	}
+
+	err = s.repo.Create(ctx, user)
+	if err != nil {
+		if errors.Is(err, repository.ErrUsernameTaken) {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *userService) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := s.repo.GetByUsername(ctx, strings.TrimSpace(username))
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+func (s *userService) ChangePassword(ctx context.Context, userID int64, req *models.ChangePasswordRequest) error {
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.PasswordHash == "" {
+		return ErrNoPassword
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		return err
+	}
+
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.UpdatePassword(ctx, userID, hash)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// A new password is often a response to a stolen one; sessions started
+	// with the old password must not outlive it.
+	return s.tokens.RevokeUserRefreshTokens(ctx, userID)
+}
+
+func (s *userService) UpdateRole(ctx context.Context, userID int64, role models.Role) error {
+	if !role.Valid() {
+		return ErrInvalidInput
+	}
+
+	err := s.repo.UpdateRole(ctx, userID, role)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	if len(password) > maxPasswordLength {
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// hashPassword salts and hashes with bcrypt; the cost factor is stored in the
+// hash itself so it can be raised later without invalidating existing hashes.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
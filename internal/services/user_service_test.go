@@ -0,0 +1,165 @@
+package services_test
+
+import (
+	"context"
+	"task-manager/internal/models"
+	"task-manager/internal/repository"
+	"task-manager/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int64, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateRole(ctx context.Context, id int64, role models.Role) error {
+	args := m.Called(ctx, id, role)
+	return args.Error(0)
+}
+
+func TestUserServiceMethods(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockTokens := new(MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockTokens)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: 7, Username: "alice", PasswordHash: string(hash)}
+
+	t.Run("Register", func(t *testing.T) {
+		req := &models.RegisterRequest{Username: " bob ", Password: "battery staple"}
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Once()
+
+		got, err := service.Register(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, "bob", got.Username)
+		assert.Equal(t, models.RoleMember, got.Role)
+		assert.NotEqual(t, req.Password, got.PasswordHash)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(got.PasswordHash), []byte(req.Password)))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Register username taken", func(t *testing.T) {
+		req := &models.RegisterRequest{Username: "alice", Password: "battery staple"}
+		mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(repository.ErrUsernameTaken).Once()
+
+		got, err := service.Register(context.Background(), req)
+		assert.ErrorIs(t, err, services.ErrUsernameTaken)
+		assert.Nil(t, got)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Register weak password", func(t *testing.T) {
+		req := &models.RegisterRequest{Username: "carol", Password: "short"}
+
+		got, err := service.Register(context.Background(), req)
+		assert.ErrorIs(t, err, services.ErrWeakPassword)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Authenticate", func(t *testing.T) {
+		testCases := map[string]struct {
+			username string
+			password string
+			mockUser *models.User
+			wantErr  error
+		}{
+			"valid":          {username: "alice", password: "correct horse", mockUser: user},
+			"wrong password": {username: "alice", password: "wrong horse", mockUser: user, wantErr: services.ErrInvalidCredentials},
+			"unknown user":   {username: "mallory", password: "correct horse", mockUser: nil, wantErr: services.ErrInvalidCredentials},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				mockRepo.On("GetByUsername", mock.Anything, tc.username).Return(tc.mockUser, nil).Once()
+
+				got, err := service.Authenticate(context.Background(), tc.username, tc.password)
+				if tc.wantErr != nil {
+					assert.ErrorIs(t, err, tc.wantErr)
+					assert.Nil(t, got)
+				} else {
+					assert.NoError(t, err)
+					assert.Equal(t, user.ID, got.ID)
+				}
+				mockRepo.AssertExpectations(t)
+			})
+		}
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		req := &models.ChangePasswordRequest{CurrentPassword: "correct horse", NewPassword: "new battery staple"}
+		mockRepo.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+		mockRepo.On("UpdatePassword", mock.Anything, int64(7), mock.MatchedBy(func(h string) bool {
+			return bcrypt.CompareHashAndPassword([]byte(h), []byte(req.NewPassword)) == nil
+		})).Return(nil).Once()
+		mockTokens.On("RevokeUserRefreshTokens", mock.Anything, int64(7)).Return(nil).Once()
+
+		err := service.ChangePassword(context.Background(), 7, req)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockTokens.AssertExpectations(t)
+	})
+
+	t.Run("ChangePassword wrong current password", func(t *testing.T) {
+		req := &models.ChangePasswordRequest{CurrentPassword: "guess", NewPassword: "new battery staple"}
+		mockRepo.On("GetByID", mock.Anything, int64(7)).Return(user, nil).Once()
+
+		err := service.ChangePassword(context.Background(), 7, req)
+		assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ChangePassword single sign-on account", func(t *testing.T) {
+		req := &models.ChangePasswordRequest{CurrentPassword: "", NewPassword: "new battery staple"}
+		mockRepo.On("GetByID", mock.Anything, int64(8)).Return(&models.User{ID: 8, Username: "sso"}, nil).Once()
+
+		err := service.ChangePassword(context.Background(), 8, req)
+		assert.ErrorIs(t, err, services.ErrNoPassword)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateRole", func(t *testing.T) {
+		mockRepo.On("UpdateRole", mock.Anything, int64(7), models.RoleViewer).Return(nil).Once()
+
+		err := service.UpdateRole(context.Background(), 7, models.RoleViewer)
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("UpdateRole invalid role", func(t *testing.T) {
+		err := service.UpdateRole(context.Background(), 7, models.Role("owner"))
+		assert.ErrorIs(t, err, services.ErrInvalidInput)
+	})
+}
@@ -0,0 +1,49 @@
+// Package storage holds the blob stores attachment contents are kept in.
+// Metadata lives in Postgres; a store only maps opaque keys to bytes.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrBlobNotFound = errors.New("blob not found")
+	ErrInvalidKey   = errors.New("invalid blob key")
+)
+
+// BlobStore is implemented by each place attachment contents can live. Keys
+// are slash-separated paths made of letters, digits, '-', '_' and '.', and
+// never contain "..".
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing any existing
+	// blob. A failed Put leaves no partial blob behind.
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey reports whether key is safe to hand to any BlobStore.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > 255 || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
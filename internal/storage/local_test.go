@@ -0,0 +1,16 @@
+package storage_test
+
+import (
+	"task-manager/internal/storage"
+	"task-manager/internal/storage/storagetest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	storagetest.Run(t, store)
+}
@@ -0,0 +1,76 @@
+// Package storagetest checks that a storage.BlobStore behaves the way the
+// attachment service relies on. Every implementation should pass it: the
+// local store against a temporary directory, and a remote one such as an
+// S3-compatible store against a local stand-in server.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"task-manager/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run exercises store. It only uses keys under "storagetest/".
+func Run(t *testing.T, store storage.BlobStore) {
+	ctx := context.Background()
+
+	t.Run("round trip", func(t *testing.T) {
+		data := bytes.Repeat([]byte("blob"), 4096)
+		require.NoError(t, store.Put(ctx, "storagetest/1/a.bin", bytes.NewReader(data)))
+
+		rc, err := store.Get(ctx, "storagetest/1/a.bin")
+		require.NoError(t, err)
+		defer rc.Close()
+		got, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "storagetest/2", bytes.NewReader([]byte("old"))))
+		require.NoError(t, store.Put(ctx, "storagetest/2", bytes.NewReader([]byte("new"))))
+
+		rc, err := store.Get(ctx, "storagetest/2")
+		require.NoError(t, err)
+		defer rc.Close()
+		got, _ := io.ReadAll(rc)
+		assert.Equal(t, "new", string(got))
+	})
+
+	t.Run("failed put leaves nothing", func(t *testing.T) {
+		err := store.Put(ctx, "storagetest/3", io.MultiReader(bytes.NewReader([]byte("partial")), failingReader{}))
+		require.Error(t, err)
+
+		_, err = store.Get(ctx, "storagetest/3")
+		assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.Put(ctx, "storagetest/4", bytes.NewReader([]byte("x"))))
+		require.NoError(t, store.Delete(ctx, "storagetest/4"))
+
+		_, err := store.Get(ctx, "storagetest/4")
+		assert.ErrorIs(t, err, storage.ErrBlobNotFound)
+		assert.NoError(t, store.Delete(ctx, "storagetest/4"))
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		for _, key := range []string{"", "../escape", "storagetest/../../escape", "/abs", "storagetest/", "sp ace"} {
+			assert.ErrorIs(t, store.Put(ctx, key, bytes.NewReader(nil)), storage.ErrInvalidKey, key)
+			_, err := store.Get(ctx, key)
+			assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+		}
+	})
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
@@ -0,0 +1,198 @@
+// Package validation checks request structs against their validate tags and
+// collects what is wrong with each field.
+//
+// The supported rules are "required", which rejects zero values, and
+// "min=N" and "max=N", which bound the length of strings (in characters)
+// and slices, and the value of numbers. Fields are named by their JSON name.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field error codes.
+const (
+	CodeRequired   = "required"
+	CodeTooShort   = "too_short"
+	CodeTooLong    = "too_long"
+	CodeInvalid    = "invalid"
+	CodeOutOfRange = "out_of_range"
+)
+
+// FieldError describes what is wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field of a request. A nil Errors means the
+// request is valid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, f := range e {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Add records a problem with field, unless one is already recorded for it;
+// the first problem found is the most basic.
+func (e *Errors) Add(field, code, message string) {
+	if e.Has(field) {
+		return
+	}
+	*e = append(*e, FieldError{Field: field, Code: code, Message: message})
+}
+
+func (e Errors) Has(field string) bool {
+	for _, f := range e {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Without returns e less any problem recorded for field.
+func (e Errors) Without(field string) Errors {
+	var out Errors
+	for _, f := range e {
+		if f.Field != field {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Err returns e as an error, or nil if it is empty.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Struct checks the validate tags of the struct v points to.
+func Struct(v interface{}) Errors {
+	var errs Errors
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+		checkField(&errs, jsonName(field), rv.Field(i), tag)
+	}
+	return errs
+}
+
+func checkField(errs *Errors, name string, value reflect.Value, tag string) {
+	for _, rule := range strings.Split(tag, ",") {
+		rule, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch rule {
+		case "required":
+			if value.IsZero() {
+				errs.Add(name, CodeRequired, "is required")
+				return
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validation: bad %s=%q on %s", rule, arg, name))
+			}
+			size, ok := measure(value)
+			if !ok {
+				continue
+			}
+			if rule == "min" && size < limit {
+				errs.Add(name, tooShortOrSmall(value), "must be at least "+arg+unit(value))
+				return
+			}
+			if rule == "max" && size > limit {
+				errs.Add(name, tooLongOrLarge(value), "must be at most "+arg+unit(value))
+				return
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", rule, name))
+		}
+	}
+}
+
+// measure returns the length of a string or slice or the value of a number.
+// Nil pointers are not measured; "required" is what rejects them.
+func measure(value reflect.Value) (float64, bool) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return 0, false
+		}
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+func isNumber(value reflect.Value) bool {
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return false
+	}
+	return true
+}
+
+func tooShortOrSmall(value reflect.Value) string {
+	if isNumber(value) {
+		return CodeOutOfRange
+	}
+	return CodeTooShort
+}
+
+func tooLongOrLarge(value reflect.Value) string {
+	if isNumber(value) {
+		return CodeOutOfRange
+	}
+	return CodeTooLong
+}
+
+func unit(value reflect.Value) string {
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package validation_test
+
+import (
+	"task-manager/internal/validation"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type request struct {
+	Name  string   `json:"name" validate:"required,max=5"`
+	Note  string   `json:"note,omitempty" validate:"min=2"`
+	Count int      `json:"count" validate:"max=3"`
+	Tags  []string `validate:"max=1"`
+	Skip  string
+}
+
+func TestStruct(t *testing.T) {
+	testCases := map[string]struct {
+		req  request
+		want validation.Errors
+	}{
+		"valid": {req: request{Name: "héllo", Note: "ok", Count: 3, Tags: []string{"a"}}},
+		"missing required": {
+			req:  request{Note: "ok"},
+			want: validation.Errors{{Field: "name", Code: validation.CodeRequired, Message: "is required"}},
+		},
+		"every rule": {
+			req: request{Name: "toolong", Note: "x", Count: 4, Tags: []string{"a", "b"}},
+			want: validation.Errors{
+				{Field: "name", Code: validation.CodeTooLong, Message: "must be at most 5 characters"},
+				{Field: "note", Code: validation.CodeTooShort, Message: "must be at least 2 characters"},
+				{Field: "count", Code: validation.CodeOutOfRange, Message: "must be at most 3"},
+				{Field: "Tags", Code: validation.CodeTooLong, Message: "must be at most 1 items"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			errs := validation.Struct(&tc.req)
+			assert.Equal(t, tc.want, errs)
+			if tc.want == nil {
+				assert.NoError(t, errs.Err())
+			} else {
+				assert.Error(t, errs.Err())
+			}
+		})
+	}
+
+	t.Run("unknown rule panics", func(t *testing.T) {
+		assert.Panics(t, func() {
+			validation.Struct(&struct {
+				A string `validate:"email"`
+			}{})
+		})
+	})
+}
+
+func TestErrorsAdd(t *testing.T) {
+	var errs validation.Errors
+	errs.Add("title", validation.CodeRequired, "is required")
+	errs.Add("title", validation.CodeTooLong, "is too long")
+	errs.Add("due_date", validation.CodeOutOfRange, "is too old")
+
+	assert.Len(t, errs, 2)
+	assert.Equal(t, validation.CodeRequired, errs[0].Code)
+	assert.True(t, errs.Has("due_date"))
+	assert.Equal(t, "title: is required; due_date: is too old", errs.Error())
+
+	rest := errs.Without("title")
+	assert.False(t, rest.Has("title"))
+	assert.True(t, rest.Has("due_date"))
+	assert.Len(t, errs, 2)
+	assert.NoError(t, rest.Without("due_date").Err())
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"task-manager/internal/repository"
+	"time"
+)
+
+// IdempotencyCleanupWorker periodically deletes stored responses whose
+// Idempotency-Key window has passed.
+type IdempotencyCleanupWorker struct {
+	repo     repository.IdempotencyRepository
+	interval time.Duration
+}
+
+func NewIdempotencyCleanupWorker(repo repository.IdempotencyRepository, interval time.Duration) *IdempotencyCleanupWorker {
+	return &IdempotencyCleanupWorker{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+func (w *IdempotencyCleanupWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.repo.DeleteExpired(ctx, time.Now()); err != nil {
+				log.Printf("Error deleting expired idempotency keys: %v", err)
+			}
+		case <-ctx.Done():
+			log.Println("Idempotency cleanup worker stopped")
+			return
+		}
+	}
+}
@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"log"
+	"task-manager/internal/auth"
 	"task-manager/internal/services"
 	"time"
 )
@@ -22,6 +23,9 @@ func NewReminderWorker(taskService services.TaskService, interval, lookahead tim
 }
 
 func (w *ReminderWorker) Start(ctx context.Context) {
+	// Reminders cover every user's tasks, so the worker runs unscoped.
+	ctx = auth.NewSystemContext(ctx)
+
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
 
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"task-manager/internal/repository"
+	"time"
+)
+
+// TokenCleanupWorker periodically deletes expired refresh tokens and
+// revocation entries so those tables don't grow without bound.
+type TokenCleanupWorker struct {
+	tokenRepo repository.TokenRepository
+	interval  time.Duration
+}
+
+func NewTokenCleanupWorker(tokenRepo repository.TokenRepository, interval time.Duration) *TokenCleanupWorker {
+	return &TokenCleanupWorker{
+		tokenRepo: tokenRepo,
+		interval:  interval,
+	}
+}
+
+func (w *TokenCleanupWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.tokenRepo.DeleteExpired(ctx, time.Now()); err != nil {
+				log.Printf("Error deleting expired tokens: %v", err)
+			}
+		case <-ctx.Done():
+			log.Println("Token cleanup worker stopped")
+			return
+		}
+	}
+}
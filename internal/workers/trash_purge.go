@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"task-manager/internal/repository"
+	"task-manager/internal/storage"
+	"time"
+)
+
+// TrashPurgeWorker periodically removes tasks that have been in the trash
+// longer than the retention period, along with their attachment contents.
+type TrashPurgeWorker struct {
+	taskRepo  repository.TaskRepository
+	blobs     storage.BlobStore
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewTrashPurgeWorker(taskRepo repository.TaskRepository, blobs storage.BlobStore, retention, interval time.Duration) *TrashPurgeWorker {
+	return &TrashPurgeWorker{
+		taskRepo:  taskRepo,
+		blobs:     blobs,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+func (w *TrashPurgeWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.purge(ctx)
+		case <-ctx.Done():
+			log.Println("Trash purge worker stopped")
+			return
+		}
+	}
+}
+
+func (w *TrashPurgeWorker) purge(ctx context.Context) {
+	purged, keys, err := w.taskRepo.Purge(ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		log.Printf("Error purging trashed tasks: %v", err)
+		return
+	}
+	if purged == 0 {
+		return
+	}
+
+	for _, key := range keys {
+		if err := w.blobs.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting attachment blob %s: %v", key, err)
+		}
+	}
+	log.Printf("Purged %d trashed tasks", purged)
+}
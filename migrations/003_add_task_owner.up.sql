@@ -0,0 +1,5 @@
+-- Tasks created before accounts existed have no owner and are only visible to
+-- system processes until they are reassigned.
+ALTER TABLE tasks ADD COLUMN owner_id BIGINT REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tasks_owner_id ON tasks(owner_id);
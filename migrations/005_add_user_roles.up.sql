@@ -0,0 +1,4 @@
+-- New accounts are members. Promote the first admin by hand:
+--   UPDATE users SET role = 'admin' WHERE username = '...';
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'member', 'viewer'));
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_tasks_status;
+DROP INDEX IF EXISTS idx_tasks_is_completed;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS is_completed;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS is_completed BOOLEAN DEFAULT FALSE;
+UPDATE tasks SET is_completed = (status = 'done');
+CREATE INDEX IF NOT EXISTS idx_tasks_is_completed ON tasks(is_completed);
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS start_date;
+ALTER TABLE tasks DROP COLUMN IF EXISTS status;
+ALTER TABLE tasks DROP COLUMN IF EXISTS priority;
@@ -0,0 +1,15 @@
+ALTER TABLE tasks ADD COLUMN priority VARCHAR(10) NOT NULL DEFAULT 'medium'
+    CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+ALTER TABLE tasks ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'todo'
+    CHECK (status IN ('todo', 'in_progress', 'blocked', 'done', 'cancelled'));
+ALTER TABLE tasks ADD COLUMN start_date TIMESTAMP;
+
+UPDATE tasks SET status = 'done' WHERE is_completed;
+
+-- is_completed becomes a read-only view of status.
+DROP INDEX IF EXISTS idx_tasks_is_completed;
+ALTER TABLE tasks DROP COLUMN is_completed;
+ALTER TABLE tasks ADD COLUMN is_completed BOOLEAN GENERATED ALWAYS AS (status = 'done') STORED;
+
+CREATE INDEX idx_tasks_is_completed ON tasks(is_completed);
+CREATE INDEX idx_tasks_status ON tasks(status);
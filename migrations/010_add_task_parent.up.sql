@@ -0,0 +1,5 @@
+-- Deleting a parent leaves its children as top-level tasks unless the
+-- application deletes the whole subtree first.
+ALTER TABLE tasks ADD COLUMN parent_id BIGINT REFERENCES tasks(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_parent_id ON tasks(parent_id);
@@ -0,0 +1,9 @@
+CREATE TABLE task_dependencies (
+    task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    blocked_by_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (task_id, blocked_by_id),
+    CHECK (task_id <> blocked_by_id)
+);
+
+CREATE INDEX idx_task_dependencies_blocked_by_id ON task_dependencies(blocked_by_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tasks_recurrence_occurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS occurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS recurrence_id;
+DROP TABLE IF EXISTS task_recurrences;
@@ -0,0 +1,14 @@
+CREATE TABLE task_recurrences (
+    id BIGSERIAL PRIMARY KEY,
+    rule TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    stopped_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Each task of a series knows its position in it; the unique index keeps two
+-- completions of the same occurrence from spawning the next one twice.
+ALTER TABLE tasks ADD COLUMN recurrence_id BIGINT REFERENCES task_recurrences(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN occurrence INTEGER;
+
+CREATE UNIQUE INDEX idx_tasks_recurrence_occurrence ON tasks(recurrence_id, occurrence);
@@ -0,0 +1,5 @@
+-- Deleting a task moves it to the trash; a background job removes it for
+-- good once the retention period has passed.
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_tasks_deleted_at ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,25 @@
+-- task_events is an append-only log of every change made to a task. It has
+-- no foreign keys so the history outlives purged tasks and deleted users.
+CREATE TABLE task_events (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    actor_id BIGINT,
+    actor VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('created', 'updated', 'completed', 'deleted', 'restored')),
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_task_events_task_id_created_at ON task_events(task_id, created_at);
+CREATE INDEX idx_task_events_actor_id_created_at ON task_events(actor_id, created_at);
+CREATE INDEX idx_task_events_created_at ON task_events(created_at);
+
+CREATE FUNCTION task_events_immutable() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'task_events rows cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER task_events_immutable
+    BEFORE UPDATE OR DELETE ON task_events
+    FOR EACH ROW EXECUTE FUNCTION task_events_immutable();
@@ -0,0 +1,4 @@
+-- Support the filters and sort orders of the task listing.
+CREATE INDEX idx_tasks_owner_id_created_at ON tasks(owner_id, created_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_tasks_updated_at ON tasks(updated_at) WHERE deleted_at IS NULL;
+CREATE INDEX idx_tasks_priority ON tasks(priority);
@@ -0,0 +1,2 @@
+-- version is incremented on every write to a task and serves as its ETag.
+ALTER TABLE tasks ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+UPDATE tasks SET due_date = '0001-01-01 00:00:00' WHERE due_date IS NULL;
+ALTER TABLE tasks ALTER COLUMN due_date SET NOT NULL;
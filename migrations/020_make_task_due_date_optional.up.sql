@@ -0,0 +1,3 @@
+-- A task without a due date used to be stored with the zero time.
+ALTER TABLE tasks ALTER COLUMN due_date DROP NOT NULL;
+UPDATE tasks SET due_date = NULL WHERE due_date = '0001-01-01 00:00:00';
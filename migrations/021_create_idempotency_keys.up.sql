@@ -0,0 +1,18 @@
+-- idempotency_keys remembers the response to a write sent with an
+-- Idempotency-Key header, so a retry is answered the same way instead of
+-- being carried out again. status_code is NULL while the first request is
+-- still running.
+CREATE TABLE idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status_code INT,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    UNIQUE (user_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
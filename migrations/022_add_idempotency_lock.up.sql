@@ -0,0 +1,5 @@
+-- locked_until bounds how long a reservation may stay in progress. A process
+-- that dies mid-request never completes or releases its key; once this passes,
+-- the key can be taken over instead of answering 409 until it expires.
+ALTER TABLE idempotency_keys ADD COLUMN locked_until TIMESTAMP;
+UPDATE idempotency_keys SET locked_until = created_at WHERE status_code IS NULL;
@@ -0,0 +1,4 @@
+-- lock_token identifies the request holding a reservation. Once a stale
+-- reservation is taken over, its first holder can neither complete nor
+-- release it.
+ALTER TABLE idempotency_keys ADD COLUMN lock_token VARCHAR(64);
@@ -0,0 +1,10 @@
+#!/bin/sh
+# Applies the *.up.sql migrations in order when the database volume is first
+# created. Mounting the migrations directory into docker-entrypoint-initdb.d
+# directly would also run every *.down.sql, each just before its up file.
+set -e
+
+for f in /migrations/*.up.sql; do
+    echo "Applying $f"
+    psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" -f "$f"
+done